@@ -0,0 +1,209 @@
+package turtle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/itsmontoya/mrT"
+	"github.com/missionMeteora/toolkit/errors"
+)
+
+const (
+	// backupMagic is written at the beginning of every backup
+	backupMagic = "turtle-backup"
+	// backupVersion is the current backup format version
+	backupVersion byte = 1
+
+	// backupRecord precedes each record within a backup
+	backupRecord byte = 1
+	// backupEnd marks the end of a backup
+	backupEnd byte = 0
+
+	// maxBackupBytes is the maximum length of a key or value within a backup
+	maxBackupBytes = 1 << 30
+)
+
+// Backup will write a self-contained image of the current state to the provided writer
+// The image is taken from an immutable view of the state, so writers are never blocked.
+// Backups are restored with Restore.
+func (t *Turtle[V]) Backup(w io.Writer) (err error) {
+	if t.isClosed() {
+		// DB is closed and we cannot perform any actions, return with error
+		return errors.ErrIsClosed
+	}
+
+	if t.mfn == nil {
+		// Values cannot be marshaled, return with error
+		return ErrNoMarshalFn
+	}
+
+	var errs errors.ErrorList
+	bw := bufio.NewWriter(w)
+	if err = writeBackupHeader(bw); err != nil {
+		return
+	}
+
+	// Records are written in the same format as a snapshot of the log
+	if _, _, err = t.archiveState(newBackupWriter(bw), t.st.Load(), &errs); err != nil {
+		return
+	}
+
+	if err = errs.Err(); err != nil {
+		// A backup with missing values is not a valid image, return marshal errors
+		return
+	}
+
+	if err = bw.WriteByte(backupEnd); err != nil {
+		return
+	}
+
+	return bw.Flush()
+}
+
+// Restore will create a database at the provided path and name from a backup
+// The database must not already contain any data.
+func Restore(r io.Reader, name, path string) (err error) {
+	br := bufio.NewReader(r)
+	if err = readBackupHeader(br); err != nil {
+		return
+	}
+
+	var m *mrT.MrT
+	if m, err = mrT.New(path, name); err != nil {
+		return
+	}
+
+	if err = restore(m, br); err != nil {
+		// Close the back-end, the restore error takes precedence
+		m.Close()
+		return
+	}
+
+	return m.Close()
+}
+
+// restore will replace the log of an empty back-end with the records within a backup
+func restore(m *mrT.MrT, br *bufio.Reader) (err error) {
+	var exists bool
+	if err = m.ForEach(func(lineType byte, key, value []byte) (end bool) {
+		exists = true
+		return true
+	}); err != nil {
+		return
+	}
+
+	if exists {
+		// Database has data, restoring would mix the backup with the existing log
+		return ErrDatabaseExists
+	}
+
+	return m.Archive(func(txn *mrT.Txn) (err error) {
+		var key, value []byte
+		for {
+			var ok bool
+			if key, value, ok, err = readBackupRecord(br); err != nil || !ok {
+				return
+			}
+
+			if err = txn.Put(key, value); err != nil {
+				return
+			}
+		}
+	})
+}
+
+// newBackupWriter will return a func which writes records to a backup
+func newBackupWriter(bw *bufio.Writer) putFn {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return func(key, value []byte) (err error) {
+		if err = bw.WriteByte(backupRecord); err != nil {
+			return
+		}
+
+		if _, err = bw.Write(buf[:binary.PutUvarint(buf, uint64(len(key)))]); err != nil {
+			return
+		}
+
+		if _, err = bw.Write(key); err != nil {
+			return
+		}
+
+		if _, err = bw.Write(buf[:binary.PutUvarint(buf, uint64(len(value)))]); err != nil {
+			return
+		}
+
+		_, err = bw.Write(value)
+		return
+	}
+}
+
+// writeBackupHeader will write the backup magic and version
+func writeBackupHeader(bw *bufio.Writer) (err error) {
+	if _, err = bw.WriteString(backupMagic); err != nil {
+		return
+	}
+
+	return bw.WriteByte(backupVersion)
+}
+
+// readBackupHeader will read and validate the backup magic and version
+func readBackupHeader(br *bufio.Reader) (err error) {
+	header := make([]byte, len(backupMagic)+1)
+	if _, err = io.ReadFull(br, header); err != nil {
+		return ErrInvalidBackup
+	}
+
+	if string(header[:len(backupMagic)]) != backupMagic || header[len(backupMagic)] != backupVersion {
+		return ErrInvalidBackup
+	}
+
+	return
+}
+
+// readBackupRecord will read the next record within a backup
+// A false ok value is returned once the end of the backup has been reached
+func readBackupRecord(br *bufio.Reader) (key, value []byte, ok bool, err error) {
+	var flag byte
+	if flag, err = br.ReadByte(); err != nil {
+		// Backup ended without an end marker
+		return nil, nil, false, ErrInvalidBackup
+	}
+
+	switch flag {
+	case backupEnd:
+		return
+	case backupRecord:
+	default:
+		return nil, nil, false, ErrInvalidBackup
+	}
+
+	if key, err = readBackupBytes(br); err != nil {
+		return
+	}
+
+	if value, err = readBackupBytes(br); err != nil {
+		return
+	}
+
+	ok = true
+	return
+}
+
+// readBackupBytes will read a length prefixed byteslice
+// Lengths are untrusted, so the byteslice grows as bytes are read rather than being allocated up front.
+func readBackupBytes(br *bufio.Reader) (b []byte, err error) {
+	var n uint64
+	if n, err = binary.ReadUvarint(br); err != nil || n > maxBackupBytes {
+		return nil, ErrInvalidBackup
+	}
+
+	var buf bytes.Buffer
+	if _, err = io.CopyN(&buf, br, int64(n)); err != nil {
+		// Backup ended before the length was reached
+		return nil, ErrInvalidBackup
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,187 @@
+package turtle
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/missionMeteora/toolkit/errors"
+)
+
+const (
+	// DefaultMaxBatchSize is the default maximum number of calls within a batch
+	DefaultMaxBatchSize = 1000
+	// DefaultMaxBatchDelay is the default maximum delay before a batch is committed
+	DefaultMaxBatchDelay = 10 * time.Millisecond
+)
+
+// errTrySolo is a sentinel error used to signal that a batch call should be retried on it's own
+const errTrySolo = errors.Error("batch function returned an error and should be re-run solo")
+
+// Batch will call the provided func as part of a batch
+// Concurrent calls to Batch are gathered into a single write transaction,
+// which is committed to disk once. Each call receives it's own error.
+// A batch is committed once it holds the max batch size calls, or once the max
+// batch delay has passed (see SetMaxBatchSize and SetMaxBatchDelay).
+//
+// If the func returns an error, the actions it took are rolled back and
+// it is retried on it's own using Update. This means the func may be called
+// more than once, so it should not have side effects outside of the transaction.
+// If the func panics, the actions it took are rolled back and a *PanicError is
+// returned to the caller, the other calls within the batch are unaffected.
+func (t *Turtle[V]) Batch(fn TxnFn[V]) (err error) {
+	if t.readOnly {
+		// Read-only databases cannot perform write actions, return with error
+		return ErrReadOnly
+	}
+
+	errCh := make(chan error, 1)
+
+	t.batchMux.Lock()
+	if t.batch == nil || len(t.batch.calls) >= t.maxBatchSize {
+		// No batch exists or the current batch is full, create a new batch
+		t.batch = &batch[V]{t: t}
+		t.batch.timer = time.AfterFunc(t.maxBatchDelay, t.batch.trigger)
+	}
+
+	b := t.batch
+	b.calls = append(b.calls, call[V]{fn: fn, err: errCh})
+	if len(b.calls) >= t.maxBatchSize {
+		// Batch is full, trigger it without waiting for the timer
+		go b.trigger()
+	}
+	t.batchMux.Unlock()
+
+	if err = <-errCh; err == errTrySolo {
+		// Func failed within the batch, retry it on it's own
+		err = t.Update(fn)
+	}
+
+	return
+}
+
+// SetMaxBatchSize will set the maximum number of calls within a batch
+// Batches are committed as soon as they are full, without waiting for the max batch delay.
+func (t *Turtle[V]) SetMaxBatchSize(n int) (err error) {
+	if n <= 0 {
+		return ErrInvalidMaxBatchSize
+	}
+
+	t.batchMux.Lock()
+	defer t.batchMux.Unlock()
+	t.maxBatchSize = n
+	return
+}
+
+// SetMaxBatchDelay will set the maximum delay before a batch is committed
+// A delay of zero commits each batch as soon as possible, gathering only the calls which arrive in the meantime.
+func (t *Turtle[V]) SetMaxBatchDelay(d time.Duration) (err error) {
+	if d < 0 {
+		return ErrInvalidMaxBatchDelay
+	}
+
+	t.batchMux.Lock()
+	defer t.batchMux.Unlock()
+	t.maxBatchDelay = d
+	return
+}
+
+// batch is a group of calls which are committed within a single write transaction
+type batch[V any] struct {
+	// Parent database
+	t *Turtle[V]
+	// Delay timer
+	timer *time.Timer
+	// Ensures the batch is only run once
+	start sync.Once
+	// Gathered calls
+	calls []call[V]
+}
+
+// call is a single Batch call
+type call[V any] struct {
+	fn  TxnFn[V]
+	err chan<- error
+}
+
+// trigger will run the batch if it has not already been run
+func (b *batch[V]) trigger() {
+	b.start.Do(b.run)
+}
+
+// run will perform all the gathered calls within a single write transaction
+func (b *batch[V]) run() {
+	b.t.batchMux.Lock()
+	b.timer.Stop()
+	if b.t.batch == b {
+		// Ensure no new calls are added to this batch
+		b.t.batch = nil
+	}
+	b.t.batchMux.Unlock()
+
+	// Error for each call
+	errs := make([]error, len(b.calls))
+	err := b.t.Update(func(txn Txn[V]) (err error) {
+		w := txn.(*WTxn[V])
+		for i, c := range b.calls {
+			// Call within a sub-transaction so a failing call does not affect the others
+			errs[i] = w.Nested(func(txn Txn[V]) (err error) {
+				return b.call(w, c.fn)
+			})
+		}
+
+		return
+	})
+
+	// Batch was committed when the only error is a failed sync
+	_, notDurable := err.(*NotDurableError)
+	for i, c := range b.calls {
+		switch {
+		case err != nil && !notDurable:
+			// Batch failed, every call receives the error
+			c.err <- err
+		case errs[i] == nil:
+			// Call was committed, the error is nil unless the log could not be synced
+			c.err <- err
+		case isPanicError(errs[i]):
+			// Call panicked, retrying it would only panic again
+			c.err <- errs[i]
+		default:
+			// Call failed within the batch, signal for it to be retried solo
+			c.err <- errTrySolo
+		}
+	}
+}
+
+// call will perform a single call within the batch transaction, recovering any panic as a *PanicError
+func (b *batch[V]) call(w *WTxn[V], fn TxnFn[V]) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v}
+		}
+	}()
+
+	if err = fn(w); err != nil {
+		return
+	}
+
+	// Ensure this call does not cause the batch to violate an index
+	return w.checkIndexes()
+}
+
+// isPanicError will return whether or not an error is a *PanicError
+func isPanicError(err error) (ok bool) {
+	_, ok = err.(*PanicError)
+	return
+}
+
+// PanicError is returned by Batch when the provided func panics
+type PanicError struct {
+	// Value the func panicked with
+	Value any
+}
+
+// Error will return the error message
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("batch function panicked: %v", p.Value)
+}
@@ -0,0 +1,260 @@
+package turtle
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// bucketSep is the separator used to encode bucket names within back-end keys
+const bucketSep = "\x00"
+
+// Bucket is a named key/value space within a transaction
+type Bucket[V any] interface {
+	// Get value by key
+	Get(key string) (V, error)
+	// Put value by key
+	Put(key string, value V) error
+	// PutWithTTL value by key, the key expires after the provided duration
+	PutWithTTL(key string, value V, ttl time.Duration) error
+	// Delete key
+	Delete(key string) error
+	// ForEach key/value pair
+	ForEach(fn ForEachFn[V]) error
+	// ForEachPrefix key/value pair with a matching key prefix, in order
+	ForEachPrefix(prefix string, fn ForEachFn[V]) error
+	// ForEachRange key/value pair with a key between start (inclusive) and end (exclusive), in order
+	ForEachRange(start, end string, fn ForEachFn[V]) error
+	// Cursor will return an ordered cursor
+	Cursor() *Cursor[V]
+}
+
+// buckets is a set of named stores
+type buckets[V any] map[string]store[V]
+
+// exists will return a boolean representing if a bucket exists for a provided name
+func (b buckets[V]) exists(name string) (ok bool) {
+	_, ok = b[name]
+	return
+}
+
+// txnBuckets is a specialized set handling transaction bucket actions
+type txnBuckets[V any] map[string]*bucketAction[V]
+
+// names will return the bucket names in order
+func (t txnBuckets[V]) names() (names []string) {
+	names = make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return
+}
+
+type bucketAction[V any] struct {
+	// Transaction store for the bucket, only looked at during put state
+	ts txnStore[V]
+	// reset state, true when the original bucket contents were dropped during the transaction
+	reset bool
+	// put state, false assumes a delete action
+	put bool
+}
+
+// rbucket is a read-only view of a store
+type rbucket[V any] struct {
+	// Original store
+	s store[V]
+}
+
+// Get will get a value for a provided key
+func (r *rbucket[V]) Get(key string) (V, error) {
+	return r.s.get(key)
+}
+
+// Put will put a value for a provided key
+func (r *rbucket[V]) Put(key string, value V) error {
+	// Cannot perform PUT actions during a read transaction
+	return ErrNotWriteTxn
+}
+
+// PutWithTTL will put a value for a provided key which expires after the provided duration
+func (r *rbucket[V]) PutWithTTL(key string, value V, ttl time.Duration) error {
+	// Cannot perform PUT actions during a read transaction
+	return ErrNotWriteTxn
+}
+
+// Delete will delete a key
+func (r *rbucket[V]) Delete(key string) error {
+	// Cannot perform PUT actions during a read transaction
+	return ErrNotWriteTxn
+}
+
+// ForEach will iterate through all current items
+func (r *rbucket[V]) ForEach(fn ForEachFn[V]) (err error) {
+	return r.s.forEach(fn)
+}
+
+// ForEachPrefix will iterate in order through all current items with a matching key prefix
+func (r *rbucket[V]) ForEachPrefix(prefix string, fn ForEachFn[V]) error {
+	return forEachPrefix(r.Cursor(), prefix, fn)
+}
+
+// ForEachRange will iterate in order through all current items with a key between start (inclusive) and end (exclusive)
+func (r *rbucket[V]) ForEachRange(start, end string, fn ForEachFn[V]) error {
+	return forEachRange(r.Cursor(), start, end, fn)
+}
+
+// Cursor will return an ordered cursor
+func (r *rbucket[V]) Cursor() *Cursor[V] {
+	return newCursor[V](r.s, nil)
+}
+
+// wbucket is a writable view of a store
+type wbucket[V any] struct {
+	// Original store
+	s store[V]
+	// Transaction store
+	ts txnStore[V]
+	// Parent transaction, records changes for savepoints
+	txn *WTxn[V]
+}
+
+// Get will get a value for a provided key
+func (w *wbucket[V]) Get(key string) (value V, err error) {
+	var ok bool
+	// Attempt to get from transaction store first
+	if value, ok, err = w.ts.get(key); ok || err != nil {
+		// We've encountered two situations:
+		//	1. We've found the value (ok is true)
+		//	2. The value has been deleted during this transaction (err == ErrKeyDoesNotExist)
+		return
+	}
+
+	// Return results from get called directly on store
+	return w.s.get(key)
+}
+
+// Put will put a value for a provided key
+func (w *wbucket[V]) Put(key string, value V) (err error) {
+	return w.put(key, value, 0)
+}
+
+// PutWithTTL will put a value for a provided key which expires after the provided duration
+func (w *wbucket[V]) PutWithTTL(key string, value V, ttl time.Duration) (err error) {
+	if ttl <= 0 {
+		// Expiry would be in the past, return early with error
+		return ErrInvalidTTL
+	}
+
+	return w.put(key, value, time.Now().Add(ttl).UnixNano())
+}
+
+// put will put a value for a provided key with a provided expiry
+func (w *wbucket[V]) put(key string, value V, expires int64) (err error) {
+	if strings.HasPrefix(key, bucketSep) {
+		// Leading null bytes are reserved for bucket encoding
+		return ErrInvalidKey
+	}
+
+	w.txn.recordKey(w, key)
+	w.ts[key] = &action[V]{
+		put:     true,
+		value:   value,
+		expires: expires,
+	}
+
+	return
+}
+
+// Delete will delete a key
+func (w *wbucket[V]) Delete(key string) (err error) {
+	if !w.s.exists(key) && !w.ts.exists(key) {
+		// This key does not exist within the store nor the transaction
+		// TODO: Add a better deletion use-case for transaction-only finds
+		return
+	}
+
+	// No value is needed as this is a delete action
+	w.txn.recordKey(w, key)
+	w.ts[key] = &action[V]{
+		put: false,
+	}
+	return
+}
+
+// ForEach will iterate through all current items
+func (w *wbucket[V]) ForEach(fn ForEachFn[V]) (err error) {
+	var ok bool
+	now := time.Now().UnixNano()
+	for key, action := range w.ts {
+		if !action.put || action.isExpired(now) {
+			// Action was not a PUT action, which means it was a delete action
+			// Or, the put action has already expired
+			continue
+		}
+
+		if fn(key, action.value) {
+			// End was called, return early
+			return
+		}
+	}
+
+	return w.s.forEach(func(key string, value V) (end bool) {
+		if _, ok = w.ts[key]; ok {
+			// This key already exists within our transaction map, we can continue on
+			return
+		}
+
+		return fn(key, value)
+	})
+}
+
+// ForEachPrefix will iterate in order through all current items with a matching key prefix
+func (w *wbucket[V]) ForEachPrefix(prefix string, fn ForEachFn[V]) error {
+	return forEachPrefix(w.Cursor(), prefix, fn)
+}
+
+// ForEachRange will iterate in order through all current items with a key between start (inclusive) and end (exclusive)
+func (w *wbucket[V]) ForEachRange(start, end string, fn ForEachFn[V]) error {
+	return forEachRange(w.Cursor(), start, end, fn)
+}
+
+// Cursor will return an ordered cursor
+// Pending actions are captured when the cursor is created
+func (w *wbucket[V]) Cursor() *Cursor[V] {
+	return newCursor[V](w.s, w.ts)
+}
+
+// isValidBucketName will return whether or not a bucket name can be encoded
+func isValidBucketName(name string) bool {
+	return len(name) > 0 && !strings.Contains(name, bucketSep)
+}
+
+// getBucketKey will return the back-end key which marks the existence of a bucket
+func getBucketKey(name string) string {
+	return bucketSep + name
+}
+
+// getBucketEntryKey will return the back-end key for a key within a bucket
+func getBucketEntryKey(name, key string) string {
+	return bucketSep + name + bucketSep + key
+}
+
+// parseKey will parse a back-end key into it's bucket name and key
+// Root keys return an empty bucket name, bucket markers return an empty key and isMarker as true
+func parseKey(bkey string) (name, key string, isMarker bool) {
+	if !strings.HasPrefix(bkey, bucketSep) {
+		// Key does not belong to a bucket
+		return "", bkey, false
+	}
+
+	bkey = bkey[len(bucketSep):]
+	idx := strings.Index(bkey, bucketSep)
+	if idx == -1 {
+		// No separator exists, this is a bucket marker
+		return bkey, "", true
+	}
+
+	return bkey[:idx], bkey[idx+len(bucketSep):], false
+}
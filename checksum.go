@@ -0,0 +1,98 @@
+package turtle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// checksumSize is the size of a record checksum
+const checksumSize = 4
+
+// castagnoli is the CRC32C table
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// recordChecksum appends and verifies back-end record checksums
+// A CRC32C of the stored key and value is appended to the key of every put and delete line,
+// as delete lines do not have a value. Checksums are calculated over the bytes as they are
+// stored, after compression and encryption. A nil record checksum passes records through as-is.
+type recordChecksum struct{}
+
+// sum will return the checksum for a stored key and value
+func (r *recordChecksum) sum(key, value []byte) uint32 {
+	crc := crc32.Update(0, castagnoli, key)
+	return crc32.Update(crc, castagnoli, value)
+}
+
+// append will return the key with the checksum of the key and value appended
+func (r *recordChecksum) append(key, value []byte) []byte {
+	if r == nil {
+		return key
+	}
+
+	out := make([]byte, len(key), len(key)+checksumSize)
+	copy(out, key)
+	return binary.BigEndian.AppendUint32(out, r.sum(key, value))
+}
+
+// verify will verify the checksum of a stored key and value, returning the key without it's checksum
+func (r *recordChecksum) verify(key, value []byte) (out []byte, err error) {
+	if r == nil {
+		return key, nil
+	}
+
+	if len(key) < checksumSize {
+		return key, ErrChecksumMismatch
+	}
+
+	out = key[:len(key)-checksumSize]
+	if binary.BigEndian.Uint32(key[len(out):]) != r.sum(out, value) {
+		return out, ErrChecksumMismatch
+	}
+
+	return
+}
+
+// wrap will return a put func which appends checksums before calling the provided put func
+func (r *recordChecksum) wrap(put putFn) putFn {
+	if r == nil {
+		return put
+	}
+
+	return func(key, value []byte) error {
+		return put(r.append(key, value), value)
+	}
+}
+
+// newCorruptionError will return a corruption error for a record within the log
+func newCorruptionError(name, path string, record int64, key []byte, err error) *CorruptionError {
+	var c CorruptionError
+	c.File = logFile(name, path)
+	c.Record = record
+	c.Key = string(key)
+	c.Err = err
+	return &c
+}
+
+// CorruptionError is returned when a record within the log cannot be loaded
+type CorruptionError struct {
+	// Log file of the database
+	File string
+	// Position of the record within the log, starting from zero
+	// Note: The back-end does not expose byte offsets, records are located by their position
+	Record int64
+	// Back-end key of the record, this may be encrypted or damaged
+	Key string
+	// Underlying error
+	Err error
+}
+
+// Error will return the error message
+func (c *CorruptionError) Error() string {
+	return fmt.Sprintf("corrupt record %d (key %q) within %s: %v", c.Record, c.Key, c.File, c.Err)
+}
+
+// Unwrap will return the underlying error
+func (c *CorruptionError) Unwrap() error {
+	return c.Err
+}
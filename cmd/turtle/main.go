@@ -0,0 +1,406 @@
+// Command turtle inspects and maintains turtle databases
+// Databases are opened with the byte-slice variant, values are read and written as raw bytes.
+// Encrypted, compressed and checksummed databases must be opened with the matching flags.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/itsmontoya/turtle/types/bytes"
+)
+
+const usage = `usage: turtle [flags] <command> [arguments]
+
+commands:
+	get <key>          print the value for a key
+	put <key> <value>  set the value for a key
+	del <key>          delete a key
+	ls [prefix]        list keys, optionally matching a prefix
+	dump               write all keys as newline-delimited JSON
+	compact            replace the log with a snapshot of the current state
+	stats              print database statistics
+	verify             load the database and check every key is readable
+	repair             discard unreadable records, which are kept in a quarantine log
+
+key files:
+	A key file holds either a single hex encoded key, or one id:hexkey line for each key of a
+	key ring. The first key of a key ring is the active key, the remaining keys are used to read
+	records written before the key ring was rotated. Blank lines and lines starting with # are skipped.
+
+flags:
+`
+
+func main() {
+	var c cmd
+	fs := flag.NewFlagSet("turtle", flag.ExitOnError)
+	fs.StringVar(&c.name, "name", "", "database name (required)")
+	fs.StringVar(&c.path, "path", ".", "database directory")
+	fs.StringVar(&c.bucket, "bucket", "", "bucket name, root keys are used when empty")
+	fs.StringVar(&c.keyFile, "key-file", "", "file holding the hex encoded 32 byte key of an encrypted database, or id:hexkey lines of a key ring")
+	fs.BoolVar(&c.compress, "compress", false, "values are gzip compressed")
+	fs.BoolVar(&c.checksums, "checksums", false, "records are checksummed")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(os.Args[1:])
+	if len(c.name) == 0 || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := c.run(os.Stdout, fs.Arg(0), fs.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "turtle: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// cmd is a command invocation
+type cmd struct {
+	// Database name
+	name string
+	// Database directory
+	path string
+	// Bucket name, empty for root keys
+	bucket string
+
+	// Key file, empty unless the database is encrypted
+	keyFile string
+	// Compression state, set when values are gzip compressed
+	compress bool
+	// Checksums state, set when records are checksummed
+	checksums bool
+}
+
+// run will open the database and run the provided command
+func (c *cmd) run(w io.Writer, command string, args []string) (err error) {
+	var (
+		fn func(db *bytes.DB, w io.Writer, args []string) error
+		// Read-only state, commands which only read are opened read-only so the files are never written
+		readOnly bool
+		// Skip snapshot state, commands which write a snapshot do not need another one on close
+		skipSnapshot bool
+	)
+
+	switch command {
+	case "get":
+		fn, readOnly = c.get, true
+	case "put":
+		fn = c.put
+	case "del":
+		fn = c.del
+	case "ls":
+		fn, readOnly = c.ls, true
+	case "dump":
+		fn, readOnly = c.dump, true
+	case "compact":
+		fn, skipSnapshot = c.compact, true
+	case "stats":
+		fn, readOnly = c.stats, true
+	case "verify":
+		fn, readOnly = c.verify, true
+	case "repair":
+		// Databases cannot be open while they are repaired
+		return c.repair(w, args)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	if _, err = os.Stat(c.path); err != nil {
+		// Avoid creating a database directory for a database which does not exist
+		return
+	}
+
+	var opts []bytes.Option
+	if opts, err = c.options(); err != nil {
+		return
+	}
+
+	if readOnly {
+		opts = append(opts, bytes.WithReadOnly())
+	}
+
+	if skipSnapshot {
+		opts = append(opts, bytes.WithSnapshotOnClose(false))
+	}
+
+	var db *bytes.DB
+	if db, err = bytes.Open(c.name, c.path, opts...); err != nil {
+		return
+	}
+
+	if err = fn(db, w, args); err != nil {
+		db.Close()
+		return
+	}
+
+	return db.Close()
+}
+
+// get will print the value for a key
+func (c *cmd) get(db *bytes.DB, w io.Writer, args []string) (err error) {
+	if len(args) != 1 {
+		return fmt.Errorf("get expects 1 argument, received %d", len(args))
+	}
+
+	return db.Read(func(txn bytes.Txn) (err error) {
+		var bkt bytes.Bucket
+		if bkt, err = c.getBucket(txn); err != nil {
+			return
+		}
+
+		var b []byte
+		if b, err = bkt.Get(args[0]); err != nil {
+			return
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return
+	})
+}
+
+// put will set the value for a key
+func (c *cmd) put(db *bytes.DB, w io.Writer, args []string) (err error) {
+	if len(args) != 2 {
+		return fmt.Errorf("put expects 2 arguments, received %d", len(args))
+	}
+
+	return db.Update(func(txn bytes.Txn) (err error) {
+		var bkt bytes.Bucket
+		if bkt, err = c.getBucket(txn); err == bytes.ErrBucketDoesNotExist {
+			bkt, err = txn.CreateBucket(c.bucket)
+		}
+
+		if err != nil {
+			return
+		}
+
+		return bkt.Put(args[0], []byte(args[1]))
+	})
+}
+
+// del will delete a key
+func (c *cmd) del(db *bytes.DB, w io.Writer, args []string) (err error) {
+	if len(args) != 1 {
+		return fmt.Errorf("del expects 1 argument, received %d", len(args))
+	}
+
+	return db.Update(func(txn bytes.Txn) (err error) {
+		var bkt bytes.Bucket
+		if bkt, err = c.getBucket(txn); err != nil {
+			return
+		}
+
+		return bkt.Delete(args[0])
+	})
+}
+
+// ls will list keys in order, optionally matching a prefix
+func (c *cmd) ls(db *bytes.DB, w io.Writer, args []string) (err error) {
+	if len(args) > 1 {
+		return fmt.Errorf("ls expects at most 1 argument, received %d", len(args))
+	}
+
+	prefix := strings.Join(args, "")
+	return db.Read(func(txn bytes.Txn) (err error) {
+		var bkt bytes.Bucket
+		if bkt, err = c.getBucket(txn); err != nil {
+			return
+		}
+
+		return bkt.ForEachPrefix(prefix, func(key string, _ []byte) (end bool) {
+			_, err = fmt.Fprintln(w, key)
+			return err != nil
+		})
+	})
+}
+
+// dump will write all keys as newline-delimited JSON
+func (c *cmd) dump(db *bytes.DB, w io.Writer, args []string) (err error) {
+	return db.Export(w, bytes.FormatNDJSON)
+}
+
+// compact will replace the log with a snapshot of the current state
+func (c *cmd) compact(db *bytes.DB, w io.Writer, args []string) (err error) {
+	before := db.Stats().LogRecords
+	if err = db.Compact(); err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintf(w, "compacted %d records to %d\n", before, db.Stats().LogRecords)
+	return
+}
+
+// stats will print database statistics
+func (c *cmd) stats(db *bytes.DB, w io.Writer, args []string) (err error) {
+	s := db.Stats()
+	_, err = fmt.Fprintf(w, "keys: %d\nbuckets: %d\nbucket keys: %d\nexpiring keys: %d\nlog records: %d\ntransactions since compaction: %d\n",
+		s.Keys, s.Buckets, s.BucketKeys, s.Expiring, s.LogRecords, s.Transactions)
+	return
+}
+
+// verify will check every key within the database is readable
+// The log is fully parsed and every value is unmarshaled when the database is opened,
+// so any unreadable keys are reported while opening.
+func (c *cmd) verify(db *bytes.DB, w io.Writer, args []string) (err error) {
+	s := db.Stats()
+	_, err = fmt.Fprintf(w, "ok: %d keys, %d buckets, %d bucket keys\n", s.Keys, s.Buckets, s.BucketKeys)
+	return
+}
+
+// repair will repair the database and print the discarded records
+func (c *cmd) repair(w io.Writer, args []string) (err error) {
+	if len(args) != 0 {
+		return fmt.Errorf("repair expects 0 arguments, received %d", len(args))
+	}
+
+	var kp bytes.KeyProvider
+	if kp, err = c.key(); err != nil {
+		return
+	}
+
+	opts := bytes.RepairOptions{Key: kp, Checksums: c.checksums, Quarantine: true}
+	if c.compress {
+		opts.Compressor = bytes.GzipCompressor{}
+	}
+
+	var r *bytes.RepairReport
+	if r, err = bytes.Repair(c.name, c.path, opts); err != nil {
+		return
+	}
+
+	for _, cerr := range r.Discarded {
+		fmt.Fprintf(w, "discarded: %v\n", cerr)
+	}
+
+	if r.Truncated != nil {
+		fmt.Fprintf(w, "truncated after record %d: %v\n", r.Records, r.Truncated)
+	}
+
+	if !r.Repaired() {
+		_, err = fmt.Fprintf(w, "ok: %d records, nothing to repair\n", r.Records)
+		return
+	}
+
+	if len(r.Quarantine) > 0 {
+		fmt.Fprintf(w, "discarded records written to %s\n", r.Quarantine)
+	}
+
+	_, err = fmt.Fprintf(w, "repaired: %d records, %d discarded\n", r.Records, len(r.Discarded))
+	return
+}
+
+// options will return the options for the database, based on the flags
+func (c *cmd) options() (opts []bytes.Option, err error) {
+	opts = append(opts, bytes.WithCodec(marshal, unmarshal))
+	var kp bytes.KeyProvider
+	if kp, err = c.key(); err != nil {
+		return
+	}
+
+	if kp != nil {
+		opts = append(opts, bytes.WithEncryption(kp))
+	}
+
+	if c.compress {
+		opts = append(opts, bytes.WithCompression(bytes.GzipCompressor{}))
+	}
+
+	if c.checksums {
+		opts = append(opts, bytes.WithChecksums())
+	}
+
+	return
+}
+
+// key will return the key provider for the key file, nil is returned when no key file is set
+// A single hex encoded key is returned as a static key, id:hexkey lines are returned as a key ring
+// with the first key set as the active key.
+func (c *cmd) key() (kp bytes.KeyProvider, err error) {
+	if len(c.keyFile) == 0 {
+		return
+	}
+
+	var b []byte
+	if b, err = os.ReadFile(c.keyFile); err != nil {
+		return
+	}
+
+	var kr *bytes.KeyRing
+	for i, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); len(line) == 0 || strings.HasPrefix(line, "#") {
+			// Blank line or comment, skip
+			continue
+		}
+
+		id, hexKey, ok := strings.Cut(line, ":")
+		if !ok {
+			if kp != nil || kr != nil {
+				// A plain key must be the only key within the file
+				return nil, fmt.Errorf("invalid key file: line %d: expected id:hexkey", i+1)
+			}
+
+			var key []byte
+			if key, err = hex.DecodeString(line); err != nil {
+				return nil, fmt.Errorf("invalid key file: line %d: %v", i+1, err)
+			}
+
+			kp = bytes.StaticKey(key)
+			continue
+		}
+
+		if kp != nil || len(id) == 0 {
+			return nil, fmt.Errorf("invalid key file: line %d: expected id:hexkey", i+1)
+		}
+
+		var key []byte
+		if key, err = hex.DecodeString(hexKey); err != nil {
+			return nil, fmt.Errorf("invalid key file: line %d: %v", i+1, err)
+		}
+
+		if kr == nil {
+			// First key of the key ring, set as the active key
+			kr = bytes.NewKeyRing(id, key)
+			continue
+		}
+
+		if _, err = kr.Key(id); err == nil {
+			return nil, fmt.Errorf("invalid key file: line %d: duplicate key id %q", i+1, id)
+		}
+
+		kr.Add(id, key)
+	}
+
+	if kr != nil {
+		kp = kr
+	}
+
+	if kp == nil {
+		return nil, fmt.Errorf("invalid key file: no keys found")
+	}
+
+	return kp, nil
+}
+
+// getBucket will return the bucket for the command, the root bucket is returned when no bucket is set
+func (c *cmd) getBucket(txn bytes.Txn) (bytes.Bucket, error) {
+	if len(c.bucket) == 0 {
+		return txn, nil
+	}
+
+	return txn.Bucket(c.bucket)
+}
+
+func marshal(b []byte) ([]byte, error) {
+	return b, nil
+}
+
+func unmarshal(b []byte) ([]byte, error) {
+	return b, nil
+}
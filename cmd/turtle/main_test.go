@@ -0,0 +1,225 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itsmontoya/turtle/types/bytes"
+)
+
+func TestCommands(t *testing.T) {
+	c := cmd{name: "commands", path: "./data"}
+	defer os.RemoveAll("./data")
+
+	if _, err := c.exec("get", "0"); !os.IsNotExist(err) {
+		t.Fatalf("invalid error, expected a not exist error and received %v", err)
+	}
+
+	if err := os.MkdirAll(c.path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		bucket  string
+		command string
+		args    []string
+		out     string
+	}{
+		{command: "put", args: []string{"0", "John Doe"}},
+		{command: "put", args: []string{"1", `{"name":"Jane Doe"}`}},
+		{bucket: "foo", command: "put", args: []string{"2", "Foo"}},
+		{command: "get", args: []string{"0"}, out: "John Doe\n"},
+		{bucket: "foo", command: "get", args: []string{"2"}, out: "Foo\n"},
+		{command: "ls", out: "0\n1\n"},
+		{command: "ls", args: []string{"1"}, out: "1\n"},
+		{command: "del", args: []string{"0"}},
+		{command: "ls", out: "1\n"},
+		{command: "dump", out: `{"key":"1","value":{"name":"Jane Doe"}}` + "\n" + `{"bucket":"foo"}` + "\n" + `{"bucket":"foo","key":"2","value":"Rm9v","encoding":"base64"}` + "\n"},
+		{command: "stats", out: "keys: 1\nbuckets: 1\nbucket keys: 1\nexpiring keys: 0\nlog records: 4\ntransactions since compaction: 0\n"},
+		{command: "compact", out: "compacted 4 records to 4\n"},
+		{command: "verify", out: "ok: 1 keys, 1 buckets, 1 bucket keys\n"},
+		{command: "repair", out: "ok: 4 records, nothing to repair\n"},
+	}
+
+	for _, tt := range tests {
+		c.bucket = tt.bucket
+		out, err := c.exec(tt.command, tt.args...)
+		if err != nil {
+			t.Fatalf("error running %s: %v", tt.command, err)
+		}
+
+		if out != tt.out {
+			t.Fatalf("invalid output for %s %v, expected %q and received %q", tt.command, tt.args, tt.out, out)
+		}
+	}
+
+	if _, err := c.exec("get", "0"); err != bytes.ErrKeyDoesNotExist {
+		t.Fatalf("invalid error, expected %v and received %v", bytes.ErrKeyDoesNotExist, err)
+	}
+
+	if _, err := c.exec("get"); err == nil {
+		t.Fatal("expected error for missing argument")
+	}
+
+	if _, err := c.exec("foo"); err == nil {
+		t.Fatal("expected error for unknown command")
+	}
+}
+
+func TestReadOnlyCommands(t *testing.T) {
+	c := cmd{name: "readonly", path: "./data"}
+	defer os.RemoveAll("./data")
+
+	if err := os.MkdirAll(c.path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.exec("put", "0", "John Doe"); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := os.ReadFile(filepath.Join(c.path, "readonly.tdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading commands must never write to the database
+	for _, command := range []string{"get", "ls", "dump", "stats", "verify"} {
+		var args []string
+		if command == "get" {
+			args = []string{"0"}
+		}
+
+		if _, err = c.exec(command, args...); err != nil {
+			t.Fatalf("error running %s: %v", command, err)
+		}
+	}
+
+	after, err := os.ReadFile(filepath.Join(c.path, "readonly.tdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(before) != string(after) {
+		t.Fatal("expected log to be left as-is by reading commands")
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(c.path, "readonly.values.*")); len(files) != 0 {
+		t.Fatalf("expected no files to be created, found %v", files)
+	}
+}
+
+func TestOptionCommands(t *testing.T) {
+	defer os.RemoveAll("./data")
+	if err := os.MkdirAll("./data", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join("./data", "key")
+	if err := os.WriteFile(keyFile, []byte(strings.Repeat("01", 32)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []cmd{
+		{name: "encrypted", path: "./data", keyFile: keyFile},
+		{name: "compressed", path: "./data", compress: true},
+		{name: "checksummed", path: "./data", checksums: true},
+		{name: "all", path: "./data", keyFile: keyFile, compress: true, checksums: true},
+	} {
+		if _, err := c.exec("put", "0", strings.Repeat("John Doe ", 10)); err != nil {
+			t.Fatalf("error running put for %s: %v", c.name, err)
+		}
+
+		for _, command := range []string{"get", "dump", "compact", "repair"} {
+			var args []string
+			if command == "get" {
+				args = []string{"0"}
+			}
+
+			if _, err := c.exec(command, args...); err != nil {
+				t.Fatalf("error running %s for %s: %v", command, c.name, err)
+			}
+		}
+
+		// Databases cannot be opened without the matching flags
+		plain := cmd{name: c.name, path: c.path}
+		if _, err := plain.exec("get", "0"); err != bytes.ErrFormatMismatch {
+			t.Fatalf("invalid error for %s, expected %v and received %v", c.name, bytes.ErrFormatMismatch, err)
+		}
+	}
+
+	bad := filepath.Join("./data", "bad")
+	if err := os.WriteFile(bad, []byte("not hex"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := cmd{name: "encrypted", path: "./data", keyFile: bad}
+	if _, err := c.exec("get", "0"); err == nil {
+		t.Fatal("expected error for invalid key file")
+	}
+}
+
+func TestKeyRingCommands(t *testing.T) {
+	defer os.RemoveAll("./data")
+	if err := os.MkdirAll("./data", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join("./data", "keys")
+	writeKeys := func(lines ...string) {
+		if err := os.WriteFile(keyFile, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := "a:" + strings.Repeat("01", 32)
+	b := "b:" + strings.Repeat("02", 32)
+	c := cmd{name: "keyring", path: "./data", keyFile: keyFile}
+
+	writeKeys(a)
+	if _, err := c.exec("put", "0", "John Doe"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the active key, records written with the previous key remain readable
+	writeKeys("# active key first", b, "", a)
+	if _, err := c.exec("put", "1", "Jane Doe"); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := c.exec("get", "0"); err != nil || out != "John Doe\n" {
+		t.Fatalf("invalid get, expected %q and received %q (%v)", "John Doe\n", out, err)
+	}
+
+	if _, err := c.exec("compact"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every record has been rewritten with the active key, the previous key is no longer needed
+	writeKeys(b)
+	if out, err := c.exec("ls"); err != nil || out != "0\n1\n" {
+		t.Fatalf("invalid ls, expected %q and received %q (%v)", "0\n1\n", out, err)
+	}
+
+	for _, lines := range [][]string{
+		{a, strings.Repeat("01", 32)},
+		{a, a},
+		{":" + strings.Repeat("01", 32)},
+		{"a:not hex"},
+		{"# no keys"},
+	} {
+		writeKeys(lines...)
+		if _, err := c.exec("get", "0"); err == nil {
+			t.Fatalf("expected error for invalid key file %q", lines)
+		}
+	}
+}
+
+// exec will run a command and return it's output
+func (c *cmd) exec(command string, args ...string) (out string, err error) {
+	var sb strings.Builder
+	err = c.run(&sb, command, args)
+	return sb.String(), err
+}
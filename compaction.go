@@ -0,0 +1,373 @@
+package turtle
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/itsmontoya/mrT"
+	"github.com/missionMeteora/toolkit/errors"
+)
+
+// MinCompactionSize is the minimum size of the log before SizeRatio triggers a compaction
+// This prevents small databases from compacting on nearly every commit
+const MinCompactionSize = 1 << 20
+
+// CompactionPolicy determines when the log is automatically compacted
+// A compaction occurs when any of the enabled triggers are met, zero values disable a trigger.
+type CompactionPolicy struct {
+	// Interval will compact on a timer, when changes have been committed since the last compaction
+	Interval time.Duration
+	// Transactions will compact after the provided number of committed transactions
+	Transactions uint64
+	// SizeRatio will compact when the log is more than SizeRatio times the size of the live data
+	// Sizes are the total size of the keys and values of the records, as they are stored within the log.
+	// The live data is the records holding the current value of each key, expiry lines and bucket markers
+	// are only counted within the log. A ratio of 2 compacts once over half of the log is stale.
+	SizeRatio float64
+}
+
+// isEnabled will return whether or not any compaction triggers are enabled
+func (p *CompactionPolicy) isEnabled() bool {
+	return p.Interval > 0 || p.Transactions > 0 || p.SizeRatio > 0
+}
+
+// SetCompactionPolicy will set the automatic compaction policy
+// Any previously running compactor is stopped, a zero policy disables automatic compaction.
+func (t *Turtle[V]) SetCompactionPolicy(p CompactionPolicy) (err error) {
+	if t.readOnly {
+		// Read-only databases cannot be compacted, return with error
+		return ErrReadOnly
+	}
+
+	if p.SizeRatio < 0 {
+		return ErrInvalidCompactionPolicy
+	}
+
+	t.compactMux.Lock()
+	defer t.compactMux.Unlock()
+
+	if t.isClosed() {
+		// DB is closed and we cannot perform any actions, return with error
+		return errors.ErrIsClosed
+	}
+
+	if t.compactStop != nil {
+		// Stop the current compactor
+		close(t.compactStop)
+		t.compactStop = nil
+	}
+
+	if !p.isEnabled() {
+		// Automatic compaction is disabled
+		return
+	}
+
+	t.compactStop = make(chan struct{})
+	go t.compactor(p, t.compactStop)
+	return
+}
+
+// Compact will replace the log with a snapshot of the current state
+// The snapshot is written to a spill file from an immutable view of the state, so neither readers nor writers
+// are blocked while values are marshaled. Writers are only blocked while the log is replaced with the spill file,
+// followed by the transactions which were committed while the snapshot was being written.
+func (t *Turtle[V]) Compact() (err error) {
+	if t.readOnly {
+		// Read-only databases cannot be compacted, return with error
+		return ErrReadOnly
+	}
+
+	if t.isClosed() {
+		// DB is closed and we cannot perform any actions, return with error
+		return errors.ErrIsClosed
+	}
+
+	return t.compact()
+}
+
+// compact will replace the log with a snapshot of the current state, without holding the write-lock while
+// the snapshot is written (see Compact)
+func (t *Turtle[V]) compact() (err error) {
+	// Only a single snapshot is written at a time
+	t.archiveMux.Lock()
+	defer t.archiveMux.Unlock()
+
+	if t.isClosed() {
+		// DB was closed while we were waiting for the lock, the snapshot was taken on close
+		return errors.ErrIsClosed
+	}
+
+	t.mux.Lock()
+	// Ensure records are sealed with the active key
+	if err = t.c.refresh(); err != nil {
+		t.mux.Unlock()
+		return
+	}
+
+	// Journal the transactions committed after the state which is being archived
+	st := t.st.Load()
+	vs := t.vs
+	j := &journal{}
+	t.journal = j
+	t.mux.Unlock()
+
+	var (
+		sp    *spill
+		nv    *values[V]
+		moved map[*valueRef[V]]*valueRef[V]
+		errs  errors.ErrorList
+	)
+
+	if sp, err = t.spill(st, &errs); err == nil && vs != nil {
+		// Rewrite the value file from the same state, values committed in the meantime are copied once the log is replaced
+		if nv, moved, err = vs.rewrite(st); err != nil {
+			sp.close()
+		}
+	}
+
+	// Acquire write-lock, this blocks writers while the log is replaced
+	t.mux.Lock()
+	// Defer release of write-lock
+	defer t.mux.Unlock()
+	// Stop journaling
+	t.journal = nil
+	if err != nil {
+		return
+	}
+
+	defer sp.close()
+
+	var records, size uint64
+	if err = t.mrT.Archive(func(txn *mrT.Txn) (err error) {
+		if records, size, err = sp.replay(txn.Put); err != nil {
+			return
+		}
+
+		return j.replay(txn)
+	}); err != nil {
+		if nv != nil {
+			nv.discard()
+		}
+
+		errs.Push(err)
+		return errs.Err()
+	}
+
+	t.archived(records+uint64(len(j.lines)), size+j.size, j.txns, &errs)
+	if nv != nil {
+		errs.Push(t.swapValues(nv, moved))
+	}
+
+	return errs.Err()
+}
+
+// notifyCompactor will signal the compactor that a transaction was committed
+// The signal is dropped when the compactor already has a pending signal
+func (t *Turtle[V]) notifyCompactor() {
+	select {
+	case t.compactSignal <- struct{}{}:
+	default:
+	}
+}
+
+// compactor will compact the log based on the provided policy until stopped or the database is closed
+func (t *Turtle[V]) compactor(p CompactionPolicy, stop chan struct{}) {
+	var tc <-chan time.Time
+	if p.Interval > 0 {
+		tkr := time.NewTicker(p.Interval)
+		defer tkr.Stop()
+		tc = tkr.C
+	}
+
+	for {
+		var due bool
+		select {
+		case <-tc:
+			// Only compact when changes have been committed since the last compaction
+			due = atomic.LoadUint64(&t.txns) > 0
+		case <-t.compactSignal:
+			due = t.isCompactionDue(&p)
+		case <-stop:
+			return
+		case <-t.done:
+			return
+		}
+
+		if !due {
+			continue
+		}
+
+		if err := t.Compact(); err != nil && !t.isClosed() {
+			t.logf("turtle: error encountered while compacting: %v", err)
+		}
+	}
+}
+
+// isCompactionDue will return whether or not the transaction or size ratio triggers of a policy have been met
+func (t *Turtle[V]) isCompactionDue(p *CompactionPolicy) bool {
+	if p.Transactions > 0 && atomic.LoadUint64(&t.txns) >= p.Transactions {
+		return true
+	}
+
+	if p.SizeRatio <= 0 {
+		return false
+	}
+
+	size := atomic.LoadUint64(&t.logSize)
+	if size < MinCompactionSize {
+		return false
+	}
+
+	return float64(size) > p.SizeRatio*float64(t.st.Load().size())
+}
+
+// size will return the size of the records holding the current value of each key
+func (st *state[V]) size() (n int64) {
+	n = st.s.size
+	for _, s := range st.b {
+		n += s.size
+	}
+
+	return
+}
+
+// recordSize will return the size of a record as it is stored within the log
+func recordSize(key, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
+
+// countSize will return a put func which adds the size of each record to the provided total
+func countSize(put putFn, size *uint64) putFn {
+	return func(key, value []byte) error {
+		*size += uint64(recordSize(key, value))
+		return put(key, value)
+	}
+}
+
+// spill will write an archive of the provided state to a spill file
+// The spill file uses the backup format, it is removed once it has been replayed into the log.
+func (t *Turtle[V]) spill(st *state[V], errs *errors.ErrorList) (sp *spill, err error) {
+	var s spill
+	if s.f, err = os.Create(filepath.Join(t.path, t.name+".compact")); err != nil {
+		return
+	}
+
+	bw := bufio.NewWriter(s.f)
+	if _, _, err = t.archiveState(newBackupWriter(bw), st, errs); err == nil {
+		if err = bw.WriteByte(backupEnd); err == nil {
+			err = bw.Flush()
+		}
+	}
+
+	if err != nil {
+		s.close()
+		return
+	}
+
+	return &s, nil
+}
+
+// spill is an archive of a state written to disk, so that it does not need to be held in memory
+type spill struct {
+	f *os.File
+}
+
+// replay will put every record within the spill file, returning the number and size of the records
+func (s *spill) replay(put putFn) (records, size uint64, err error) {
+	if _, err = s.f.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	br := bufio.NewReader(s.f)
+	for {
+		var (
+			key, value []byte
+			ok         bool
+		)
+
+		if key, value, ok, err = readBackupRecord(br); err != nil || !ok {
+			return
+		}
+
+		if err = put(key, value); err != nil {
+			return
+		}
+
+		records++
+		size += uint64(recordSize(key, value))
+	}
+}
+
+// close will close and remove the spill file
+func (s *spill) close() error {
+	s.f.Close()
+	return os.Remove(s.f.Name())
+}
+
+// journal holds the lines of the transactions committed while a snapshot is being written
+// The lines are written after the snapshot, so the replaced log holds every committed transaction.
+type journal struct {
+	// Lines in the order they were committed
+	lines []journalLine
+	// Total size of the lines
+	size uint64
+	// Number of journaled transactions
+	txns uint64
+}
+
+// journalLine is a single line written to the log
+type journalLine struct {
+	lineType byte
+	key      []byte
+	value    []byte
+}
+
+// push will add the lines of a committed transaction to the journal
+func (j *journal) push(jt *journalTxn) {
+	for _, l := range jt.lines {
+		j.size += uint64(recordSize(l.key, l.value))
+	}
+
+	j.lines = append(j.lines, jt.lines...)
+	j.txns++
+}
+
+// replay will write every line within the journal to the provided back-end transaction
+func (j *journal) replay(txn logTxn) (err error) {
+	for _, l := range j.lines {
+		if l.lineType == mrT.DeleteLine {
+			err = txn.Delete(l.key)
+		} else {
+			err = txn.Put(l.key, l.value)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// journalTxn is a back-end transaction which records the lines written to it
+type journalTxn struct {
+	txn   logTxn
+	lines []journalLine
+}
+
+// Put will record and write a put line
+// Lines are copied, as the back-end may retain or reuse the provided byteslices
+func (j *journalTxn) Put(key, value []byte) error {
+	j.lines = append(j.lines, journalLine{lineType: mrT.PutLine, key: append([]byte(nil), key...), value: append([]byte(nil), value...)})
+	return j.txn.Put(key, value)
+}
+
+// Delete will record and write a delete line
+func (j *journalTxn) Delete(key []byte) error {
+	j.lines = append(j.lines, journalLine{lineType: mrT.DeleteLine, key: append([]byte(nil), key...)})
+	return j.txn.Delete(key)
+}
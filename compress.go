@@ -0,0 +1,135 @@
+package turtle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+const (
+	// recordRaw flags a record value which is not compressed
+	recordRaw byte = 0
+	// recordCompressed flags a record value which is compressed
+	recordCompressed byte = 1
+)
+
+// Compressor compresses and decompresses record values
+type Compressor interface {
+	// Compress will return the compressed bytes
+	Compress(b []byte) ([]byte, error)
+	// Decompress will return the decompressed bytes
+	Decompress(b []byte) ([]byte, error)
+}
+
+// GzipCompressor is a gzip Compressor
+type GzipCompressor struct {
+	// Compression level, zero uses the default compression level
+	Level int
+}
+
+// Compress will return the gzip compressed bytes
+func (g GzipCompressor) Compress(b []byte) (out []byte, err error) {
+	level := g.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var (
+		buf bytes.Buffer
+		w   *gzip.Writer
+	)
+
+	if w, err = gzip.NewWriterLevel(&buf, level); err != nil {
+		return
+	}
+
+	if _, err = w.Write(b); err != nil {
+		return
+	}
+
+	if err = w.Close(); err != nil {
+		return
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress will return the gzip decompressed bytes
+func (g GzipCompressor) Decompress(b []byte) (out []byte, err error) {
+	var r *gzip.Reader
+	if r, err = gzip.NewReader(bytes.NewReader(b)); err != nil {
+		return
+	}
+
+	if out, err = io.ReadAll(r); err != nil {
+		return
+	}
+
+	return out, r.Close()
+}
+
+// recordCompressor compresses and decompresses back-end record values
+// Each value is prefixed with a flag, values are only stored compressed when compression
+// reduces their size, so compressed and uncompressed records coexist within the log.
+// A nil record compressor passes values through as-is.
+type recordCompressor struct {
+	c Compressor
+}
+
+// encode will return the flagged (and possibly compressed) value
+func (r *recordCompressor) encode(value []byte) (out []byte, err error) {
+	if r == nil {
+		return value, nil
+	}
+
+	var cb []byte
+	if cb, err = r.c.Compress(value); err != nil {
+		return
+	}
+
+	if len(cb) < len(value) {
+		out = make([]byte, 0, len(cb)+1)
+		out = append(out, recordCompressed)
+		return append(out, cb...), nil
+	}
+
+	// Compression did not reduce the size, store as-is
+	out = make([]byte, 0, len(value)+1)
+	out = append(out, recordRaw)
+	return append(out, value...), nil
+}
+
+// decode will return the original value of a flagged value
+func (r *recordCompressor) decode(value []byte) (out []byte, err error) {
+	if r == nil {
+		return value, nil
+	}
+
+	if len(value) == 0 {
+		return nil, ErrInvalidCompressionFlag
+	}
+
+	switch value[0] {
+	case recordRaw:
+		return value[1:], nil
+	case recordCompressed:
+		return r.c.Decompress(value[1:])
+	default:
+		return nil, ErrInvalidCompressionFlag
+	}
+}
+
+// wrap will return a put func which encodes record values before calling the provided put func
+func (r *recordCompressor) wrap(put putFn) putFn {
+	if r == nil {
+		return put
+	}
+
+	return func(key, value []byte) (err error) {
+		if value, err = r.encode(value); err != nil {
+			return
+		}
+
+		return put(key, value)
+	}
+}
@@ -0,0 +1,370 @@
+package turtle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/missionMeteora/toolkit/errors"
+)
+
+const (
+	// envelopeVersion is the current record envelope version
+	envelopeVersion byte = 1
+
+	// staticKeyID is the key ID of a StaticKey
+	staticKeyID = "static"
+)
+
+// KeyProvider provides the versioned keys used to encrypt records
+// Keys are AES-256 keys and must be 32 bytes, key IDs cannot be longer than 255 bytes.
+type KeyProvider interface {
+	// ActiveKey will return the ID and key used to encrypt new records
+	ActiveKey() (id string, key []byte, err error)
+	// Key will return the key for a key ID, used to decrypt records
+	Key(id string) ([]byte, error)
+}
+
+// StaticKey is a KeyProvider for a single fixed key
+type StaticKey []byte
+
+// ActiveKey will return the static key
+func (k StaticKey) ActiveKey() (id string, key []byte, err error) {
+	return staticKeyID, k, nil
+}
+
+// Key will return the static key for the static key ID
+func (k StaticKey) Key(id string) ([]byte, error) {
+	if id != staticKeyID {
+		return nil, ErrKeyNotFound
+	}
+
+	return k, nil
+}
+
+// NewKeyRing will return a new key ring with the provided key set as the active key
+func NewKeyRing(id string, key []byte) *KeyRing {
+	var k KeyRing
+	k.keys = map[string][]byte{id: key}
+	k.active = id
+	return &k
+}
+
+// KeyRing is a KeyProvider for a set of versioned keys
+type KeyRing struct {
+	mux sync.RWMutex
+	// Active key ID
+	active string
+	// Keys by ID
+	keys map[string][]byte
+}
+
+// Add will add a key to the key ring
+func (k *KeyRing) Add(id string, key []byte) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	k.keys[id] = key
+}
+
+// SetActive will set the key used to encrypt new records
+func (k *KeyRing) SetActive(id string) (err error) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return ErrKeyNotFound
+	}
+
+	k.active = id
+	return
+}
+
+// Remove will remove a key from the key ring, the active key cannot be removed
+// Keys should only be removed once every record using them has been rewritten (see Turtle.Rekey)
+func (k *KeyRing) Remove(id string) (err error) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if id == k.active {
+		return ErrKeyIsActive
+	}
+
+	delete(k.keys, id)
+	return
+}
+
+// ActiveKey will return the active key
+func (k *KeyRing) ActiveKey() (id string, key []byte, err error) {
+	k.mux.RLock()
+	defer k.mux.RUnlock()
+	return k.active, k.keys[k.active], nil
+}
+
+// Key will return the key for a key ID
+func (k *KeyRing) Key(id string) (key []byte, err error) {
+	k.mux.RLock()
+	defer k.mux.RUnlock()
+	var ok bool
+	if key, ok = k.keys[id]; !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return
+}
+
+// Rekey will rewrite the log with every record encrypted by the active key of the key provider
+// The log is rewritten through the same archive path as a compaction, once it has completed the
+// previous keys are no longer needed. Records remain readable with their original keys until then.
+func (t *Turtle[V]) Rekey() (err error) {
+	if t.c == nil {
+		return ErrNotEncrypted
+	}
+
+	if t.readOnly {
+		// Read-only databases cannot be rewritten, return with error
+		return ErrReadOnly
+	}
+
+	if t.isClosed() {
+		// DB is closed and we cannot perform any actions, return with error
+		return errors.ErrIsClosed
+	}
+
+	// Snapshots are always sealed with the current active key
+	return t.compact()
+}
+
+// newRecordCipher will return a new record cipher for a key provider
+func newRecordCipher(kp KeyProvider) (rp *recordCipher, err error) {
+	var r recordCipher
+	r.kp = kp
+	r.keys = make(map[string]*recordKey)
+	if err = r.refresh(); err != nil {
+		return
+	}
+
+	rp = &r
+	return
+}
+
+// newRecordKey will return a new record key
+func newRecordKey(id string, key []byte) (rp *recordKey, err error) {
+	if len(id) > 255 {
+		return nil, ErrInvalidKeyID
+	}
+
+	if len(key) != 32 {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	var block cipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return
+	}
+
+	var r recordKey
+	r.id = id
+	if r.aead, err = cipher.NewGCM(block); err != nil {
+		return
+	}
+
+	rp = &r
+	return
+}
+
+// recordKey is a versioned AES-256-GCM key
+type recordKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// recordCipher encrypts and decrypts back-end records using AES-256-GCM
+// Keys and values are sealed separately within an envelope holding the key ID and a random nonce.
+// Values are sealed with their plaintext key as additional data, so a value cannot be moved to
+// another key. A nil record cipher passes records through as-is.
+type recordCipher struct {
+	// Key provider
+	kp KeyProvider
+	// Key used to seal new records
+	active atomic.Pointer[recordKey]
+
+	// Keys mutex
+	mux sync.Mutex
+	// Keys by ID, used to open records
+	keys map[string]*recordKey
+}
+
+// refresh will ensure the active key matches the active key of the key provider
+func (r *recordCipher) refresh() (err error) {
+	if r == nil {
+		return
+	}
+
+	var (
+		id  string
+		key []byte
+	)
+
+	if id, key, err = r.kp.ActiveKey(); err != nil {
+		return
+	}
+
+	if cur := r.active.Load(); cur != nil && cur.id == id {
+		// Active key has not changed
+		return
+	}
+
+	var rk *recordKey
+	if rk, err = newRecordKey(id, key); err != nil {
+		return
+	}
+
+	r.mux.Lock()
+	r.keys[id] = rk
+	r.mux.Unlock()
+	r.active.Store(rk)
+	return
+}
+
+// key will return the record key for a key ID
+func (r *recordCipher) key(id string) (rk *recordKey, err error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var ok bool
+	if rk, ok = r.keys[id]; ok {
+		return
+	}
+
+	var key []byte
+	if key, err = r.kp.Key(id); err != nil {
+		return
+	}
+
+	if rk, err = newRecordKey(id, key); err != nil {
+		return
+	}
+
+	r.keys[id] = rk
+	return
+}
+
+// seal will encrypt a back-end key and value
+func (r *recordCipher) seal(key, value []byte) (ekey, evalue []byte, err error) {
+	if r == nil {
+		return key, value, nil
+	}
+
+	rk := r.active.Load()
+	if ekey, err = rk.encrypt(key, nil); err != nil {
+		return
+	}
+
+	evalue, err = rk.encrypt(value, key)
+	return
+}
+
+// sealKey will encrypt a back-end key, used for lines without a value
+func (r *recordCipher) sealKey(key []byte) ([]byte, error) {
+	if r == nil {
+		return key, nil
+	}
+
+	return r.active.Load().encrypt(key, nil)
+}
+
+// sealBytes will encrypt bytes which are not stored within the log, such as the value file
+func (r *recordCipher) sealBytes(b []byte) ([]byte, error) {
+	if r == nil {
+		return b, nil
+	}
+
+	return r.active.Load().encrypt(b, nil)
+}
+
+// openBytes will decrypt bytes which were encrypted by sealBytes
+func (r *recordCipher) openBytes(b []byte) ([]byte, error) {
+	if r == nil {
+		return b, nil
+	}
+
+	return r.decrypt(b, nil)
+}
+
+// open will decrypt a back-end key and value, lines without a value return a nil value
+func (r *recordCipher) open(ekey, evalue []byte) (key, value []byte, err error) {
+	if r == nil {
+		return ekey, evalue, nil
+	}
+
+	if key, err = r.decrypt(ekey, nil); err != nil || len(evalue) == 0 {
+		return
+	}
+
+	value, err = r.decrypt(evalue, key)
+	return
+}
+
+// wrap will return a put func which seals records before calling the provided put func
+func (r *recordCipher) wrap(put putFn) putFn {
+	if r == nil {
+		return put
+	}
+
+	return func(key, value []byte) (err error) {
+		if key, value, err = r.seal(key, value); err != nil {
+			return
+		}
+
+		return put(key, value)
+	}
+}
+
+// decrypt will decrypt an envelope using the key it was sealed with
+func (r *recordCipher) decrypt(envelope, data []byte) (out []byte, err error) {
+	if len(envelope) < 2 || envelope[0] != envelopeVersion || len(envelope) < 2+int(envelope[1]) {
+		return nil, ErrDecrypt
+	}
+
+	idLen := int(envelope[1])
+	var rk *recordKey
+	if rk, err = r.key(string(envelope[2 : 2+idLen])); err != nil {
+		return
+	}
+
+	return rk.decrypt(envelope[2+idLen:], data)
+}
+
+// encrypt will encrypt the plaintext within an envelope
+// Envelopes are the version, the key ID length, the key ID, the nonce and the ciphertext
+func (rk *recordKey) encrypt(plaintext, data []byte) (out []byte, err error) {
+	ns := rk.aead.NonceSize()
+	hl := 2 + len(rk.id)
+	out = make([]byte, hl+ns, hl+ns+len(plaintext)+rk.aead.Overhead())
+	out[0] = envelopeVersion
+	out[1] = byte(len(rk.id))
+	copy(out[2:], rk.id)
+
+	nonce := out[hl:]
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return
+	}
+
+	return rk.aead.Seal(out, nonce, plaintext, data), nil
+}
+
+// decrypt will decrypt a nonce and ciphertext which was encrypted by encrypt
+func (rk *recordKey) decrypt(ciphertext, data []byte) (out []byte, err error) {
+	ns := rk.aead.NonceSize()
+	if len(ciphertext) < ns+rk.aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+
+	if out, err = rk.aead.Open(nil, ciphertext[:ns], ciphertext[ns:], data); err != nil {
+		// Key is incorrect or the record has been modified
+		return nil, ErrDecrypt
+	}
+
+	return
+}
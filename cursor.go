@@ -0,0 +1,177 @@
+package turtle
+
+import (
+	"strings"
+	"time"
+)
+
+// newCursor will return a new cursor for a store and an optional transaction store
+func newCursor[V any](s store[V], ts txnStore[V]) *Cursor[V] {
+	var c Cursor[V]
+	c.s = s
+	// Capture the pending actions, so that changes made while the cursor is in use
+	// (including rolling back to a savepoint) do not affect the cursor
+	c.ts = ts.copy()
+	c.tidx = c.ts.keys()
+	c.now = time.Now().UnixNano()
+	return &c
+}
+
+// Cursor is an ordered iterator over keys
+// Within a write transaction, the pending actions are merged in order
+type Cursor[V any] struct {
+	// Original store
+	s store[V]
+	// Copy of the transaction store, nil for read transactions
+	ts txnStore[V]
+	// Ordered index of the transaction store keys
+	tidx index
+	// Creation time as unix nanoseconds, used to hide expired keys
+	now int64
+
+	// Current key
+	key string
+	// Valid state, false when the cursor is not pointing at a key
+	valid bool
+	// Error encountered while reading a disk-resident value
+	err error
+}
+
+// forward will move the cursor to the first visible key at or after the provided store node and index position
+func (c *Cursor[V]) forward(n *node[V], j int) (key string, value V, ok bool) {
+	for ; n != nil && c.skipNode(n); n = c.s.root.ceil(n.key, true) {
+		// Key has a pending action (it will be handled by the transaction index) or has expired
+	}
+
+	for ; j < len(c.tidx) && c.skipAction(c.tidx[j]); j++ {
+		// Key is pending deletion or has expired, skip
+	}
+
+	switch {
+	case n != nil && (j == len(c.tidx) || n.key < c.tidx[j]):
+		return c.setNode(n)
+	case j < len(c.tidx):
+		key = c.tidx[j]
+		return c.set(key, c.ts[key].value)
+	}
+
+	// We've reached the end
+	c.valid = false
+	return
+}
+
+// backward will move the cursor to the last visible key at or before the provided store node and index position
+func (c *Cursor[V]) backward(n *node[V], j int) (key string, value V, ok bool) {
+	for ; n != nil && c.skipNode(n); n = c.s.root.floor(n.key, true) {
+		// Key has a pending action (it will be handled by the transaction index) or has expired
+	}
+
+	for ; j >= 0 && c.skipAction(c.tidx[j]); j-- {
+		// Key is pending deletion or has expired, skip
+	}
+
+	switch {
+	case n != nil && (j == -1 || n.key > c.tidx[j]):
+		return c.setNode(n)
+	case j >= 0:
+		key = c.tidx[j]
+		return c.set(key, c.ts[key].value)
+	}
+
+	// We've reached the beginning
+	c.valid = false
+	return
+}
+
+// skipNode will return whether or not a store node should be skipped
+func (c *Cursor[V]) skipNode(n *node[V]) bool {
+	return c.ts.exists(n.key) || n.isExpired(c.now)
+}
+
+// skipAction will return whether or not a transaction store key should be skipped
+func (c *Cursor[V]) skipAction(key string) bool {
+	a := c.ts[key]
+	return !a.put || a.isExpired(c.now)
+}
+
+// set will point the cursor at a provided key
+func (c *Cursor[V]) set(key string, value V) (string, V, bool) {
+	c.key = key
+	c.valid = true
+	return key, value, true
+}
+
+// setNode will point the cursor at a provided store node
+// When the value cannot be read, the cursor is no longer valid and the error is available from Err
+func (c *Cursor[V]) setNode(n *node[V]) (key string, value V, ok bool) {
+	if value, c.err = n.load(); c.err != nil {
+		c.valid = false
+		return
+	}
+
+	return c.set(n.key, value)
+}
+
+// Err will return the error encountered while reading a disk-resident value, if any
+func (c *Cursor[V]) Err() error {
+	return c.err
+}
+
+// First will move the cursor to the first key
+func (c *Cursor[V]) First() (key string, value V, ok bool) {
+	return c.forward(c.s.root.first(), 0)
+}
+
+// Last will move the cursor to the last key
+func (c *Cursor[V]) Last() (key string, value V, ok bool) {
+	return c.backward(c.s.root.last(), len(c.tidx)-1)
+}
+
+// Seek will move the cursor to the first key which is greater than or equal to the provided key
+func (c *Cursor[V]) Seek(key string) (string, V, bool) {
+	return c.forward(c.s.root.ceil(key, false), c.tidx.ceil(key))
+}
+
+// Next will move the cursor to the next key
+func (c *Cursor[V]) Next() (key string, value V, ok bool) {
+	if !c.valid {
+		// Cursor is not pointing at a key, return early
+		return
+	}
+
+	return c.forward(c.s.root.ceil(c.key, true), c.tidx.after(c.key))
+}
+
+// Prev will move the cursor to the previous key
+func (c *Cursor[V]) Prev() (key string, value V, ok bool) {
+	if !c.valid {
+		// Cursor is not pointing at a key, return early
+		return
+	}
+
+	return c.backward(c.s.root.floor(c.key, true), c.tidx.before(c.key))
+}
+
+// forEachPrefix will iterate in order through all the keys beginning with a provided prefix
+func forEachPrefix[V any](c *Cursor[V], prefix string, fn ForEachFn[V]) (err error) {
+	for key, value, ok := c.Seek(prefix); ok && strings.HasPrefix(key, prefix); key, value, ok = c.Next() {
+		if fn(key, value) {
+			// End was called, return early
+			return
+		}
+	}
+
+	return c.Err()
+}
+
+// forEachRange will iterate in order through all the keys from start (inclusive) to end (exclusive)
+func forEachRange[V any](c *Cursor[V], start, end string, fn ForEachFn[V]) (err error) {
+	for key, value, ok := c.Seek(start); ok && key < end; key, value, ok = c.Next() {
+		if fn(key, value) {
+			// End was called, return early
+			return
+		}
+	}
+
+	return c.Err()
+}
@@ -0,0 +1,243 @@
+package turtle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/missionMeteora/toolkit/errors"
+)
+
+const (
+	// syncNone never syncs the log, the operating system decides when it is written to stable storage
+	syncNone uint8 = iota
+	// syncCommit syncs the log before each commit returns
+	syncCommit
+	// syncInterval syncs the log periodically
+	syncInterval
+)
+
+var (
+	// NoSync will never sync the log, this is the default
+	// Committed transactions survive the process exiting (or crashing), as they have been written to
+	// the operating system. They may be lost if the operating system crashes or the machine loses power.
+	NoSync = Durability{}
+
+	// SyncEveryCommit will sync the log before each commit returns
+	// When Update returns nil, the transaction is on stable storage and survives an operating system
+	// crash or power loss. Each commit waits for the disk, so writes are considerably slower.
+	// A *NotDurableError is returned when the transaction was committed but the sync failed.
+	SyncEveryCommit = Durability{mode: syncCommit}
+)
+
+// SyncInterval will sync the log periodically, when transactions have been committed since the last sync
+// Transactions committed within the last interval may be lost if the operating system crashes or the
+// machine loses power, they survive the process exiting (or crashing) as with NoSync.
+func SyncInterval(d time.Duration) Durability {
+	return Durability{mode: syncInterval, interval: d}
+}
+
+// Durability determines when the log is synced to stable storage
+// Whichever durability is set, the log is synced whenever it is replaced (by Close, Compact or Rekey)
+// unless NoSync is set, and Sync may be called at any time.
+// Note: The back-end does not expose it's file, so the log is synced by opening it by name. This relies
+// on each transaction being written to the operating system before the back-end returns from it.
+type Durability struct {
+	mode     uint8
+	interval time.Duration
+}
+
+// SetDurability will set when the log is synced to stable storage
+// Any previously running interval syncer is stopped, unsynced transactions are synced first.
+func (t *Turtle[V]) SetDurability(d Durability) (err error) {
+	if t.readOnly {
+		// Read-only databases are never written, return with error
+		return ErrReadOnly
+	}
+
+	if d.mode == syncInterval && d.interval <= 0 {
+		return ErrInvalidDurability
+	}
+
+	// Acquire write-lock
+	t.mux.Lock()
+	// Defer release of write-lock
+	defer t.mux.Unlock()
+
+	if t.isClosed() {
+		// DB is closed and we cannot perform any actions, return with error
+		return errors.ErrIsClosed
+	}
+
+	if t.syncStop != nil {
+		// Stop the current syncer
+		close(t.syncStop)
+		t.syncStop = nil
+	}
+
+	if t.unsynced {
+		// Ensure transactions committed under the previous durability are not left behind
+		if err = t.syncLog(false); err != nil {
+			return
+		}
+
+		t.unsynced = false
+	}
+
+	t.durability = d
+	if d.mode != syncInterval {
+		return
+	}
+
+	t.syncStop = make(chan struct{})
+	go t.syncer(d.interval, t.syncStop)
+	return
+}
+
+// Sync will sync the log to stable storage
+// Every transaction which has been committed is durable once Sync returns nil.
+func (t *Turtle[V]) Sync() (err error) {
+	if t.readOnly {
+		// Read-only databases are never written, return with error
+		return ErrReadOnly
+	}
+
+	// Acquire write-lock, the log cannot be replaced while it is synced
+	t.mux.Lock()
+	// Defer release of write-lock
+	defer t.mux.Unlock()
+
+	if t.isClosed() {
+		// DB is closed and we cannot perform any actions, return with error
+		return errors.ErrIsClosed
+	}
+
+	if err = t.syncLog(false); err != nil {
+		return
+	}
+
+	t.unsynced = false
+	return
+}
+
+// syncer will periodically sync the log until it is stopped or the database is closed
+func (t *Turtle[V]) syncer(interval time.Duration, stop chan struct{}) {
+	tkr := time.NewTicker(interval)
+	defer tkr.Stop()
+
+	for {
+		select {
+		case <-tkr.C:
+		case <-stop:
+			return
+		case <-t.done:
+			return
+		}
+
+		if err := t.syncDue(); err != nil && !t.isClosed() {
+			t.logf("turtle: error encountered while syncing: %v", err)
+		}
+	}
+}
+
+// syncDue will sync the log when transactions have been committed since the last sync
+func (t *Turtle[V]) syncDue() (err error) {
+	// Acquire write-lock
+	t.mux.Lock()
+	// Defer release of write-lock
+	defer t.mux.Unlock()
+
+	if !t.unsynced || t.isClosed() {
+		// Nothing to sync
+		return
+	}
+
+	if err = t.syncLog(false); err != nil {
+		return
+	}
+
+	t.unsynced = false
+	return
+}
+
+// syncCommitted will apply the durability to a transaction which has been written to the log
+// Note: The write-lock is expected to be held by the caller
+func (t *Turtle[V]) syncCommitted() (err error) {
+	switch t.durability.mode {
+	case syncCommit:
+		if err = t.syncLog(false); err != nil {
+			// Leave the transaction to be synced by Sync, or when the log is replaced
+			t.unsynced = true
+		}
+	case syncInterval:
+		t.unsynced = true
+	}
+
+	return
+}
+
+// syncArchived will apply the durability to a log which has been replaced
+// The directory is synced as well, so the replacement of the log is durable.
+// Note: The write-lock is expected to be held by the caller
+func (t *Turtle[V]) syncArchived() (err error) {
+	if t.durability.mode == syncNone {
+		return
+	}
+
+	if err = t.syncLog(true); err != nil {
+		return
+	}
+
+	t.unsynced = false
+	return
+}
+
+// syncLog will sync the log, and optionally the database directory, to stable storage
+func (t *Turtle[V]) syncLog(dir bool) (err error) {
+	if err = t.syncFn(logFile(t.name, t.path)); err != nil || !dir {
+		return
+	}
+
+	return t.syncFn(t.path)
+}
+
+// NotDurableError is returned when a transaction was committed, but the log could not be synced
+// The transaction is not rolled back. It has been written to the log and is visible to readers,
+// watchers have been notified, and it will be loaded when the database is opened again. It may
+// be lost if the operating system crashes or the machine loses power before the log reaches
+// stable storage. Sync can be called to retry the sync, the transaction should not be retried.
+type NotDurableError struct {
+	// Underlying sync error
+	Err error
+}
+
+// Error will return the error message
+func (n *NotDurableError) Error() string {
+	return fmt.Sprintf("transaction committed but not durable, the log could not be synced: %v", n.Err)
+}
+
+// Unwrap will return the underlying sync error
+func (n *NotDurableError) Unwrap() error {
+	return n.Err
+}
+
+// logFile will return the file the back-end stores the log within
+func logFile(name, path string) string {
+	return filepath.Join(path, name+".tdb")
+}
+
+// syncFile will sync a file (or directory) to stable storage
+func syncFile(filename string) (err error) {
+	var f *os.File
+	if f, err = os.Open(filename); err != nil {
+		return
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return
+	}
+
+	return f.Close()
+}
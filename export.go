@@ -0,0 +1,277 @@
+package turtle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/missionMeteora/toolkit/errors"
+)
+
+const (
+	// FormatNDJSON is newline-delimited JSON, each line is a single key/value object
+	FormatNDJSON Format = iota
+)
+
+const (
+	// encodingBase64 is the line encoding used for values which cannot be embedded as JSON
+	encodingBase64 = "base64"
+)
+
+// Format is an export and import format
+type Format uint8
+
+// ProgressFn is called after each import chunk is committed with the total number of imported keys
+type ProgressFn func(imported int)
+
+// exportLine is a single key/value within an export
+// A line with a bucket name and no value is a bucket record, which is written before the
+// keys of each bucket so that empty buckets are exported.
+type exportLine struct {
+	// Bucket name, empty for root keys
+	Bucket string `json:"bucket,omitempty"`
+	// Key
+	Key string `json:"key,omitempty"`
+	// Marshaled value, base64 encoded as a JSON string when Encoding is set
+	Value json.RawMessage `json:"value,omitempty"`
+	// Value encoding, empty when the value is JSON
+	Encoding string `json:"encoding,omitempty"`
+	// Expiry as unix nanoseconds, zero represents no expiry
+	Expires int64 `json:"expires,omitempty"`
+}
+
+// Export will write all keys within the database to the provided writer
+// The export is taken from an immutable view of the state, so writers are never blocked.
+// Values are marshaled with the MarshalFn. Values which are compact JSON are embedded as-is, all other
+// values are base64 encoded so that they are imported byte-for-byte.
+func (t *Turtle[V]) Export(w io.Writer, format Format) (err error) {
+	if format != FormatNDJSON {
+		return ErrInvalidFormat
+	}
+
+	if t.isClosed() {
+		// DB is closed and we cannot perform any actions, return with error
+		return errors.ErrIsClosed
+	}
+
+	if t.mfn == nil {
+		// Values cannot be marshaled, return with error
+		return ErrNoMarshalFn
+	}
+
+	st := t.st.Load()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	// Embedded values must not be altered
+	enc.SetEscapeHTML(false)
+	if err = t.exportStore(enc, "", st.s); err != nil {
+		return
+	}
+
+	names := make([]string, 0, len(st.b))
+	for name := range st.b {
+		names = append(names, name)
+	}
+
+	// Buckets are exported in order so that exports are deterministic
+	sort.Strings(names)
+	for _, name := range names {
+		// Bucket record, so that the bucket is imported even when it is empty
+		if err = enc.Encode(exportLine{Bucket: name}); err != nil {
+			return
+		}
+
+		if err = t.exportStore(enc, name, st.b[name]); err != nil {
+			return
+		}
+	}
+
+	return bw.Flush()
+}
+
+// exportStore will write all the unexpired items within a store to an encoder
+func (t *Turtle[V]) exportStore(enc *json.Encoder, name string, s store[V]) (err error) {
+	now := time.Now().UnixNano()
+	s.root.walk(func(n *node[V]) (end bool) {
+		if n.isExpired(now) {
+			// Expired items are not exported
+			return
+		}
+
+		var b []byte
+		if b, err = n.marshal(t.mfn); err != nil {
+			return true
+		}
+
+		line := exportLine{Bucket: name, Key: n.key, Expires: n.expires}
+		if isCompactJSON(b) {
+			line.Value = b
+		} else {
+			// Value would be altered when embedded, encode as a base64 string
+			line.Value, _ = json.Marshal(base64.StdEncoding.EncodeToString(b))
+			line.Encoding = encodingBase64
+		}
+
+		err = enc.Encode(line)
+		return err != nil
+	})
+
+	return
+}
+
+// isCompactJSON will return whether or not a value is valid JSON without any insignificant whitespace
+// The encoder compacts embedded JSON, so only compact values are embedded unchanged.
+func isCompactJSON(b []byte) bool {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		// Value is not valid JSON
+		return false
+	}
+
+	return bytes.Equal(buf.Bytes(), b)
+}
+
+// Import will put all keys from the provided reader within a single transaction
+// Values are unmarshaled with the UnmarshalFn, buckets are created as needed.
+func (t *Turtle[V]) Import(r io.Reader, format Format) error {
+	return t.ImportChunked(r, format, 0, nil)
+}
+
+// ImportChunked will put all keys from the provided reader, committing a transaction
+// for every chunk of lines. The progress func is called after each committed chunk, when set.
+// Only keys which are written are counted, keys which have expired since the export are skipped.
+// A chunk size of zero imports all keys within a single transaction.
+// Note: When an error is encountered, previously committed chunks remain committed
+func (t *Turtle[V]) ImportChunked(r io.Reader, format Format, chunkSize int, fn ProgressFn) (err error) {
+	if format != FormatNDJSON {
+		return ErrInvalidFormat
+	}
+
+	var (
+		imported int
+		line     *exportLine
+	)
+
+	dec := json.NewDecoder(r)
+	// next will decode the next line, line is set to nil once the end of the reader has been reached
+	next := func() (err error) {
+		line = &exportLine{}
+		if err = dec.Decode(line); err == io.EOF {
+			line = nil
+			return nil
+		}
+
+		return
+	}
+
+	// Lines are read ahead of each chunk, so a transaction is never committed without any lines
+	if err = next(); err != nil {
+		return
+	}
+
+	for line != nil {
+		var n int
+		if err = t.Update(func(txn Txn[V]) (err error) {
+			// Lines are counted towards the chunk size, while only written keys are counted as imported
+			for lines := 0; line != nil && (chunkSize <= 0 || lines < chunkSize); lines++ {
+				var written bool
+				if written, err = t.importLine(txn, line); err != nil {
+					return
+				}
+
+				if written {
+					n++
+				}
+
+				if err = next(); err != nil {
+					return
+				}
+			}
+
+			return
+		}); err != nil {
+			return
+		}
+
+		if imported += n; fn != nil && n > 0 {
+			fn(imported)
+		}
+	}
+
+	return
+}
+
+// importLine will put a single line within a transaction
+// Written will be false for bucket records and for keys which have expired since the export.
+func (t *Turtle[V]) importLine(txn Txn[V], line *exportLine) (written bool, err error) {
+	if line.Value == nil {
+		if len(line.Bucket) == 0 {
+			// Only bucket records may omit the value
+			err = ErrInvalidFormat
+			return
+		}
+
+		// Bucket record, create the bucket
+		_, err = t.importBucket(txn, line.Bucket)
+		return
+	}
+
+	var ttl time.Duration
+	if line.Expires != 0 {
+		if ttl = time.Until(time.Unix(0, line.Expires)); ttl <= 0 {
+			// Key has expired since it was exported, skip
+			return
+		}
+	}
+
+	b := []byte(line.Value)
+	switch line.Encoding {
+	case "":
+	case encodingBase64:
+		var str string
+		if err = json.Unmarshal(line.Value, &str); err != nil {
+			return
+		}
+
+		if b, err = base64.StdEncoding.DecodeString(str); err != nil {
+			return
+		}
+
+	default:
+		err = ErrInvalidFormat
+		return
+	}
+
+	var v V
+	if v, err = t.ufn(b); err != nil {
+		return
+	}
+
+	var bkt Bucket[V] = txn
+	if len(line.Bucket) > 0 {
+		if bkt, err = t.importBucket(txn, line.Bucket); err != nil {
+			return
+		}
+	}
+
+	if ttl > 0 {
+		err = bkt.PutWithTTL(line.Key, v, ttl)
+	} else {
+		err = bkt.Put(line.Key, v)
+	}
+
+	return err == nil, err
+}
+
+// importBucket will return a bucket by name, the bucket is created when it does not exist
+func (t *Turtle[V]) importBucket(txn Txn[V], name string) (bkt Bucket[V], err error) {
+	if bkt, err = txn.Bucket(name); err == ErrBucketDoesNotExist {
+		bkt, err = txn.CreateBucket(name)
+	}
+
+	return
+}
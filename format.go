@@ -0,0 +1,92 @@
+package turtle
+
+const (
+	// formatKey is the back-end key of format header lines
+	// Bucket names cannot be empty, so this will never collide with an expiry line
+	formatKey = ttlPrefix + bucketSep
+	// formatVersion is the current format header version
+	formatVersion byte = 1
+	// formatSize is the size of a format header line
+	formatSize = len(formatKey) + 2
+)
+
+const (
+	// formatChecksums flags records which hold a checksum
+	formatChecksums format = 1 << iota
+	// formatCompressed flags records which hold a compression flag
+	formatCompressed
+	// formatEncrypted flags records which are encrypted
+	formatEncrypted
+
+	// formatAll is every known format flag
+	formatAll = formatChecksums | formatCompressed | formatEncrypted
+)
+
+// format describes how the records following a format header are stored
+// Format headers are stored as-is, rather than being checksummed, encrypted or compressed,
+// so that they can be read before the records they describe. Each archive begins with a format
+// header, further headers are written when the format of new records changes (see checkFormat).
+// Records before the first format header are stored as-is.
+type format byte
+
+// encode will return the value of a format header line
+func (f format) encode() []byte {
+	return []byte{formatVersion, byte(f)}
+}
+
+// decodeFormat will return the format of a format header line value
+func decodeFormat(value []byte) (f format, err error) {
+	if len(value) != 2 || value[0] != formatVersion || format(value[1])&^formatAll != 0 {
+		// Header is damaged or was written by a newer version
+		return 0, ErrFormatMismatch
+	}
+
+	return format(value[1]), nil
+}
+
+// isFormatKey will return whether or not a back-end key belongs to a format header line
+func isFormatKey(bkey []byte) bool {
+	return string(bkey) == formatKey
+}
+
+// getFormat will return the format of the records written by the database
+func (t *Turtle[V]) getFormat() (f format) {
+	if t.sum != nil {
+		f |= formatChecksums
+	}
+
+	if t.z != nil {
+		f |= formatCompressed
+	}
+
+	if t.c != nil {
+		f |= formatEncrypted
+	}
+
+	return
+}
+
+// checkFormat will ensure records stored in the provided format can be read by the database
+// Checksums and encryption must match, while uncompressed records can be read by a database
+// which compresses values. This allows compression to be enabled for an existing database, the
+// compressed records are written after a new format header and both coexist within the log.
+func (t *Turtle[V]) checkFormat(f format) (err error) {
+	opts := t.getFormat()
+	if (f^opts)&^formatCompressed != 0 {
+		// Records would be loaded with checksums or envelopes still attached (or missing)
+		return ErrFormatMismatch
+	}
+
+	if f&formatCompressed != 0 && opts&formatCompressed == 0 {
+		// Records hold compression flags which cannot be decoded
+		return ErrFormatMismatch
+	}
+
+	return
+}
+
+// putFormat will put a format header describing the records written by the database
+// Note: The provided put func is expected to store lines as-is
+func (t *Turtle[V]) putFormat(put putFn) error {
+	return put([]byte(formatKey), t.getFormat().encode())
+}
@@ -0,0 +1,442 @@
+package turtle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/missionMeteora/toolkit/errors"
+)
+
+// IndexFn is used to extract the index keys for a key/value pair
+type IndexFn[V any] func(key string, value V) (indexKeys []string)
+
+// secondaryIndex is an immutable secondary index
+type secondaryIndex[V any] struct {
+	// Index key extractor
+	fn IndexFn[V]
+	// Unique state, true when an index key may only belong to a single key
+	unique bool
+	// Index entries, keyed by index key and primary key (see getIndexEntryKey)
+	s store[struct{}]
+}
+
+// indexes is a set of named secondary indexes
+type indexes[V any] map[string]secondaryIndex[V]
+
+// copy will return a shallow copy of the indexes
+func (i indexes[V]) copy() (out indexes[V]) {
+	out = make(indexes[V], len(i))
+	for name, si := range i {
+		out[name] = si
+	}
+
+	return
+}
+
+// names will return the index names in order
+func (i indexes[V]) names() (names []string) {
+	names = make([]string, 0, len(i))
+	for name := range i {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return
+}
+
+// getIndexEntryKey will return the index entry key for an index key and primary key
+func getIndexEntryKey(indexKey, key string) string {
+	return indexKey + bucketSep + key
+}
+
+// isValidIndexKey will return whether or not an index key can be encoded within an index entry key
+func isValidIndexKey(indexKey string) bool {
+	return !strings.Contains(indexKey, bucketSep)
+}
+
+// add will return a copy of the index with the entries for a key/value pair added
+// Index keys which cannot be encoded are skipped
+func (si secondaryIndex[V]) add(key string, value V) secondaryIndex[V] {
+	for _, indexKey := range si.fn(key, value) {
+		if isValidIndexKey(indexKey) {
+			si.s = si.s.put(getIndexEntryKey(indexKey, key), struct{}{}, 0, 0)
+		}
+	}
+
+	return si
+}
+
+// remove will return a copy of the index with the entries for a key/value pair removed
+func (si secondaryIndex[V]) remove(key string, value V) secondaryIndex[V] {
+	for _, indexKey := range si.fn(key, value) {
+		if isValidIndexKey(indexKey) {
+			si.s = si.s.delete(getIndexEntryKey(indexKey, key))
+		}
+	}
+
+	return si
+}
+
+// updateIndexes will update all indexes for a change to a root key
+// The old node is nil when the key is new, the value is nil when the key is being deleted
+// Note: The indexes map is modified in place, callers must ensure it is not shared with readers
+func (st *state[V]) updateIndexes(key string, old *node[V], value *V) {
+	for name, si := range st.idx {
+		if old != nil {
+			// Note: Disk-resident values which cannot be read leave their entries in place
+			if oldValue, err := old.load(); err == nil {
+				si = si.remove(key, oldValue)
+			}
+		}
+
+		if value != nil {
+			si = si.add(key, *value)
+		}
+
+		st.idx[name] = si
+	}
+}
+
+// buildIndex will return an index built from the root store for an index definition
+// ErrInvalidIndexKey is returned alongside the index when any index keys cannot be encoded, they are skipped
+func (st *state[V]) buildIndex(si secondaryIndex[V]) (out secondaryIndex[V], err error) {
+	si.s = store[struct{}]{}
+	st.s.root.walk(func(n *node[V]) (end bool) {
+		value, lerr := n.load()
+		if lerr != nil {
+			return
+		}
+
+		for _, indexKey := range si.fn(n.key, value) {
+			if !isValidIndexKey(indexKey) {
+				err = ErrInvalidIndexKey
+			}
+		}
+
+		si = si.add(n.key, value)
+		return
+	})
+
+	return si, err
+}
+
+// CreateIndex will create a secondary index for the root bucket
+// The provided func is called for every key/value pair to determine it's index keys, and
+// must be deterministic. Index keys cannot contain a null byte, transactions which would write
+// such an index key fail with ErrInvalidIndexKey.
+// Indexes are not persisted, they are built from the store when they are created (and
+// whenever the store is loaded) and are kept up to date as transactions are merged.
+func (t *Turtle[V]) CreateIndex(name string, fn IndexFn[V]) (err error) {
+	return t.createIndex(name, secondaryIndex[V]{fn: fn})
+}
+
+// CreateUniqueIndex will create a secondary index for the root bucket which enforces uniqueness
+// An index key may only belong to a single key, transactions which would cause two keys to
+// share an index key fail with a *UniqueError before anything is written to disk.
+// See CreateIndex for more information regarding indexes.
+func (t *Turtle[V]) CreateUniqueIndex(name string, fn IndexFn[V]) (err error) {
+	return t.createIndex(name, secondaryIndex[V]{fn: fn, unique: true})
+}
+
+// createIndex will build and set a secondary index for an index definition
+func (t *Turtle[V]) createIndex(name string, def secondaryIndex[V]) (err error) {
+	// Acquire write-lock
+	t.mux.Lock()
+	// Defer release of write-lock
+	defer t.mux.Unlock()
+
+	if t.isClosed() {
+		// DB is closed and we cannot perform any actions, return with error
+		return errors.ErrIsClosed
+	}
+
+	cur := t.st.Load()
+	if _, ok := cur.idx[name]; ok {
+		// Index already exists, return early with error
+		return ErrIndexExists
+	}
+
+	// Copy the current state so that it remains untouched for any in-flight readers
+	st := *cur
+	var si secondaryIndex[V]
+	if si, err = st.buildIndex(def); err != nil {
+		// Existing values cannot be indexed
+		return
+	}
+
+	if si.unique {
+		// Ensure the existing keys do not violate the constraint
+		if err = si.checkUnique(name, st.s); err != nil {
+			return
+		}
+	}
+
+	st.idx = cur.idx.copy()
+	st.idx[name] = si
+	t.idxDefs[name] = def
+	t.st.Store(&st)
+	return
+}
+
+// checkUnique will ensure no index key belongs to multiple unexpired keys within a store
+func (si secondaryIndex[V]) checkUnique(name string, s store[V]) (err error) {
+	var lastIndexKey, lastKey string
+	si.s.root.walk(func(n *node[struct{}]) (end bool) {
+		idx := strings.Index(n.key, bucketSep)
+		indexKey, key := n.key[:idx], n.key[idx+len(bucketSep):]
+		if _, err = s.get(key); err != nil {
+			// Key has expired
+			err = nil
+			return
+		}
+
+		if len(lastKey) > 0 && indexKey == lastIndexKey {
+			// Index key belongs to the previous key as well
+			err = &UniqueError{Index: name, IndexKey: indexKey, Key: key, ExistingKey: lastKey}
+			return true
+		}
+
+		lastIndexKey, lastKey = indexKey, key
+		return
+	})
+
+	return
+}
+
+// uniqueClaims are the index keys claimed by the pending puts of a transaction for a unique index
+type uniqueClaims struct {
+	// Pending owners by index key
+	owners map[string]string
+	// Claimed index keys by pending key
+	claims map[string][]string
+}
+
+// markDirty will mark a root key as changed since the indexes were last checked
+func (w *WTxn[V]) markDirty(key string) {
+	if len(w.idx) == 0 {
+		// No indexes to check
+		return
+	}
+
+	if w.dirty == nil {
+		w.dirty = make(map[string]struct{})
+	}
+
+	w.dirty[key] = struct{}{}
+}
+
+// checkIndexes will ensure the pending actions do not write invalid index keys or violate any unique indexes
+// Only the keys which changed since the last check are checked, so checking after each call within a batch
+// does not re-check the calls before it.
+func (w *WTxn[V]) checkIndexes() (err error) {
+	if len(w.dirty) == 0 {
+		// Nothing has changed since the last check
+		return
+	}
+
+	// Check indexes and keys in order, so the same error is returned for the same transaction
+	keys := make([]string, 0, len(w.dirty))
+	for key := range w.dirty {
+		keys = append(keys, key)
+		delete(w.dirty, key)
+	}
+
+	sort.Strings(keys)
+	for _, name := range w.idx.names() {
+		si := w.idx[name]
+		for _, key := range keys {
+			if cerr := w.checkKey(name, si, key); cerr != nil && err == nil {
+				// Continue checking, so the claims match the pending actions
+				err = cerr
+			}
+		}
+	}
+
+	return
+}
+
+// checkKey will check the index keys for the pending action of a key, updating the claims of unique indexes
+func (w *WTxn[V]) checkKey(name string, si secondaryIndex[V], key string) (err error) {
+	var indexKeys []string
+	if action, ok := w.ts[key]; ok && action.put {
+		indexKeys = si.fn(key, action.value)
+	}
+
+	for _, indexKey := range indexKeys {
+		if !isValidIndexKey(indexKey) {
+			return ErrInvalidIndexKey
+		}
+	}
+
+	if !si.unique {
+		return
+	}
+
+	uc, ok := w.unique[name]
+	if !ok {
+		if w.unique == nil {
+			w.unique = make(map[string]*uniqueClaims)
+		}
+
+		uc = &uniqueClaims{owners: make(map[string]string), claims: make(map[string][]string)}
+		w.unique[name] = uc
+	}
+
+	// Release the index keys claimed by the previous action of the key
+	for _, indexKey := range uc.claims[key] {
+		delete(uc.owners, indexKey)
+	}
+
+	delete(uc.claims, key)
+
+	var claimed []string
+	for _, indexKey := range indexKeys {
+		if owner, ok := uc.owners[indexKey]; ok && owner != key {
+			// Index key has been claimed by another pending put
+			if err == nil {
+				err = &UniqueError{Index: name, IndexKey: indexKey, Key: key, ExistingKey: owner}
+			}
+
+			continue
+		}
+
+		if owner, ok := w.indexOwner(si, indexKey, key); ok {
+			// Index key belongs to a committed key
+			if err == nil {
+				err = &UniqueError{Index: name, IndexKey: indexKey, Key: key, ExistingKey: owner}
+			}
+
+			continue
+		}
+
+		uc.owners[indexKey] = key
+		claimed = append(claimed, indexKey)
+	}
+
+	if len(claimed) > 0 {
+		uc.claims[key] = claimed
+	}
+
+	return
+}
+
+// indexOwner will return a committed key (other than the provided key) which owns an index key
+// Keys with pending actions are ignored, as their pending values are checked separately
+func (w *WTxn[V]) indexOwner(si secondaryIndex[V], indexKey, key string) (owner string, ok bool) {
+	prefix := getIndexEntryKey(indexKey, "")
+	for n := si.s.root.ceil(prefix, false); n != nil && strings.HasPrefix(n.key, prefix); n = si.s.root.ceil(n.key, true) {
+		if owner = n.key[len(prefix):]; owner == key || w.ts.exists(owner) {
+			continue
+		}
+
+		if _, err := w.s.get(owner); err != nil {
+			// Owner has expired
+			continue
+		}
+
+		return owner, true
+	}
+
+	return "", false
+}
+
+// UniqueError is returned when a transaction would violate a unique index
+type UniqueError struct {
+	// Name of the unique index
+	Index string
+	// Index key which would be shared
+	IndexKey string
+	// Key which was being put
+	Key string
+	// Key which already owns the index key
+	ExistingKey string
+}
+
+// Error will return the error message
+func (u *UniqueError) Error() string {
+	return fmt.Sprintf("unique index %q violated: key %q cannot share index key %q with existing key %q", u.Index, u.Key, u.IndexKey, u.ExistingKey)
+}
+
+// Index is a secondary index within a transaction
+type Index[V any] struct {
+	// Index state
+	si secondaryIndex[V]
+	// Transaction store, nil for read transactions
+	ts txnStore[V]
+	// Primary key getter
+	get func(key string) (V, error)
+}
+
+// newIndex will return an index for a transaction, nil is returned if the index does not exist
+func newIndex[V any](idx indexes[V], name string, ts txnStore[V], get func(string) (V, error)) *Index[V] {
+	si, ok := idx[name]
+	if !ok {
+		return nil
+	}
+
+	return &Index[V]{si: si, ts: ts, get: get}
+}
+
+// keys will return the primary keys for an index key in order
+// Within a write transaction, the pending actions are included
+func (i *Index[V]) keys(indexKey string) (keys []string) {
+	prefix := getIndexEntryKey(indexKey, "")
+	for n := i.si.s.root.ceil(prefix, false); n != nil && strings.HasPrefix(n.key, prefix); n = i.si.s.root.ceil(n.key, true) {
+		if key := n.key[len(prefix):]; !i.ts.exists(key) {
+			// Key does not have a pending action, append
+			keys = append(keys, key)
+		}
+	}
+
+	for key, action := range i.ts {
+		if !action.put {
+			// Key is pending deletion
+			continue
+		}
+
+		for _, ik := range i.si.fn(key, action.value) {
+			if ik == indexKey {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return
+}
+
+// Get will return the primary keys for an index key in order
+func (i *Index[V]) Get(indexKey string) (keys []string, err error) {
+	err = i.ForEach(indexKey, func(key string, _ V) (end bool) {
+		keys = append(keys, key)
+		return
+	})
+
+	return
+}
+
+// ForEach will iterate in order through all the key/value pairs for an index key
+func (i *Index[V]) ForEach(indexKey string, fn ForEachFn[V]) (err error) {
+	if i == nil {
+		// Index does not exist, return early with error
+		return ErrIndexDoesNotExist
+	}
+
+	for _, key := range i.keys(indexKey) {
+		var value V
+		if value, err = i.get(key); err != nil {
+			// Key has expired, skip
+			err = nil
+			continue
+		}
+
+		if fn(key, value) {
+			// End was called, return early
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,251 @@
+package turtle
+
+import "math/rand"
+
+// node is a persistent treap node
+// Nodes are never modified once they are reachable from a store, every
+// update copies the path from the root to the affected node instead.
+type node[V any] struct {
+	key   string
+	value V
+	// Disk-resident value reference, when set the value is read from disk (see NewBounded)
+	ref *valueRef[V]
+	// Expiry as unix nanoseconds, zero represents no expiry
+	expires int64
+	// Size of the record holding the value within the log, used to measure the live data size
+	size int64
+
+	// Heap priority, used to keep the tree balanced
+	priority uint32
+
+	left  *node[V]
+	right *node[V]
+}
+
+// put will return a copy of the tree with the value, value reference, expiry and record size set for the provided key
+// The added value will be true when the key did not previously exist
+func (n *node[V]) put(key string, value V, ref *valueRef[V], expires, size int64) (out *node[V], added bool) {
+	if n == nil {
+		// We've reached the bottom of the tree, create a new node
+		out = &node[V]{key: key, value: value, ref: ref, expires: expires, size: size, priority: rand.Uint32()}
+		return out, true
+	}
+
+	// Copy the current node, the copy is not reachable by any readers yet
+	c := *n
+	switch {
+	case key < n.key:
+		if c.left, added = n.left.put(key, value, ref, expires, size); c.left.priority > c.priority {
+			// Heap order violated, rotate right
+			l := c.left
+			c.left = l.right
+			l.right = &c
+			return l, added
+		}
+
+	case key > n.key:
+		if c.right, added = n.right.put(key, value, ref, expires, size); c.right.priority > c.priority {
+			// Heap order violated, rotate left
+			r := c.right
+			c.right = r.left
+			r.left = &c
+			return r, added
+		}
+
+	default:
+		// Key matches, replace value
+		c.value = value
+		c.ref = ref
+		c.expires = expires
+		c.size = size
+	}
+
+	return &c, added
+}
+
+// delete will return a copy of the tree with the provided key removed
+// The removed value will be false when the key did not exist
+func (n *node[V]) delete(key string) (out *node[V], removed bool) {
+	if n == nil {
+		// Key does not exist
+		return nil, false
+	}
+
+	var c node[V]
+	switch {
+	case key < n.key:
+		if out, removed = n.left.delete(key); !removed {
+			// Nothing changed, we can re-use the existing node
+			return n, false
+		}
+
+		c = *n
+		c.left = out
+
+	case key > n.key:
+		if out, removed = n.right.delete(key); !removed {
+			// Nothing changed, we can re-use the existing node
+			return n, false
+		}
+
+		c = *n
+		c.right = out
+
+	default:
+		// Key matches, replace this node with it's joined children
+		return join(n.left, n.right), true
+	}
+
+	return &c, true
+}
+
+// get will return the node matching the provided key
+func (n *node[V]) get(key string) *node[V] {
+	for n != nil {
+		switch {
+		case key < n.key:
+			n = n.left
+		case key > n.key:
+			n = n.right
+		default:
+			return n
+		}
+	}
+
+	return nil
+}
+
+// ceil will return the first node with a key greater than or equal to the provided key
+// When exclusive is true, the first node with a key greater than the provided key is returned
+func (n *node[V]) ceil(key string, exclusive bool) (match *node[V]) {
+	for n != nil {
+		if key < n.key || (!exclusive && key == n.key) {
+			// Node is a candidate, attempt to find a lower one
+			match = n
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+
+	return
+}
+
+// floor will return the last node with a key less than or equal to the provided key
+// When exclusive is true, the last node with a key less than the provided key is returned
+func (n *node[V]) floor(key string, exclusive bool) (match *node[V]) {
+	for n != nil {
+		if key > n.key || (!exclusive && key == n.key) {
+			// Node is a candidate, attempt to find a higher one
+			match = n
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+
+	return
+}
+
+// first will return the node with the lowest key
+func (n *node[V]) first() *node[V] {
+	for n != nil && n.left != nil {
+		n = n.left
+	}
+
+	return n
+}
+
+// last will return the node with the highest key
+func (n *node[V]) last() *node[V] {
+	for n != nil && n.right != nil {
+		n = n.right
+	}
+
+	return n
+}
+
+// walk will iterate through the nodes of the tree in key order
+func (n *node[V]) walk(fn func(*node[V]) (end bool)) (end bool) {
+	if n == nil {
+		return
+	}
+
+	if n.left.walk(fn) {
+		return true
+	}
+
+	if fn(n) {
+		return true
+	}
+
+	return n.right.walk(fn)
+}
+
+// remap will return a copy of the tree with the value reference of every disk-resident node replaced
+func (n *node[V]) remap(fn func(*valueRef[V]) (*valueRef[V], error)) (out *node[V], err error) {
+	if n == nil {
+		return
+	}
+
+	c := *n
+	if c.left, err = n.left.remap(fn); err != nil {
+		return
+	}
+
+	if c.right, err = n.right.remap(fn); err != nil {
+		return
+	}
+
+	if n.ref != nil {
+		if c.ref, err = fn(n.ref); err != nil {
+			return
+		}
+	}
+
+	return &c, nil
+}
+
+// load will return the value of the node, disk-resident values are read through the value cache
+func (n *node[V]) load() (V, error) {
+	if n.ref == nil {
+		return n.value, nil
+	}
+
+	return n.ref.vs.get(n.ref)
+}
+
+// marshal will return the value of the node as bytes
+// Disk-resident values are already marshaled, so they are read as-is
+func (n *node[V]) marshal(mfn MarshalFn[V]) ([]byte, error) {
+	if n.ref == nil {
+		return mfn(n.value)
+	}
+
+	return n.ref.vs.read(n.ref)
+}
+
+// isExpired will return whether or not the node has expired as of the provided time
+func (n *node[V]) isExpired(now int64) bool {
+	return n.expires != 0 && n.expires <= now
+}
+
+// join will merge two trees, all the keys within l must be less than the keys within r
+func join[V any](l, r *node[V]) *node[V] {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	}
+
+	if l.priority > r.priority {
+		c := *l
+		c.right = join(l.right, r)
+		return &c
+	}
+
+	c := *r
+	c.left = join(l, r.left)
+	return &c
+}
@@ -0,0 +1,224 @@
+package turtle
+
+import (
+	"log"
+	"time"
+)
+
+// Logger is used to report errors encountered by background goroutines
+// *log.Logger satisfies this interface.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// Option is used to configure a database, see Open
+type Option func(*options)
+
+// WithCodec will set the marshal and unmarshal funcs for the value type
+// This option is required by Open, the value type must match the value type being opened.
+// Read-only databases do not require a MarshalFn.
+func WithCodec[V any](mfn MarshalFn[V], ufn UnmarshalFn[V]) Option {
+	return func(o *options) {
+		o.codec = codec[V]{mfn: mfn, ufn: ufn}
+	}
+}
+
+// WithLogger will set the logger used to report errors encountered by background goroutines
+// The standard logger is used by default.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithSnapshotOnClose will set whether or not the log is replaced with a snapshot on close
+// Snapshots are taken on close by default. When disabled, Close only releases the back-end and
+// the log is left to be compacted by Compact (or the compaction policy).
+func WithSnapshotOnClose(snapshot bool) Option {
+	return func(o *options) {
+		o.snapshotOnClose = snapshot
+	}
+}
+
+// WithReadOnly will open the database read-only
+// The log is copied to a temporary directory which the back-end loads from, and the copy is removed
+// before returning. The database files are never written to, so they may be read-only and any number
+// of read-only instances may be open at once. Read-only databases cannot be bounded (see WithBounded).
+// Write actions return ErrReadOnly, expired keys are hidden but never removed.
+// Note: Changes committed by other instances after loading are not visible
+func WithReadOnly() Option {
+	return func(o *options) {
+		o.readOnly = true
+	}
+}
+
+// WithBounded will keep values on disk
+// Only keys (and references to their values) are held in memory. Values are read on demand
+// through the UnmarshalFn and are cached within an LRU which holds up to cacheSize bytes of
+// marshaled values. A cacheSize of zero disables caching.
+// Note: Values are kept within a value file alongside the log (name.values), which is indexed on close
+// and re-used when the database is re-opened. It is rebuilt from the log when the database was not closed
+// cleanly, or when the log has changed since. The value file grows with each write, it is rewritten to
+// only hold live values each time the log is compacted.
+// The value file is encrypted when records are encrypted (see WithEncryption).
+func WithBounded(cacheSize int64) Option {
+	return func(o *options) {
+		o.bounded = true
+		o.cacheSize = cacheSize
+	}
+}
+
+// WithEncryption will encrypt records at rest
+// Keys and values are encrypted with AES-256-GCM using the active key of the provided key provider,
+// both when transactions are committed and when the log is archived. Each record holds the ID of
+// the key it was encrypted with, so rotated keys remain readable (see Rekey). ErrDecrypt is returned
+// when loading a log with the wrong key, or a log which has been tampered with. ErrFormatMismatch
+// is returned when opening an encrypted database without a key provider (or the reverse).
+// Note: Backups contain encrypted records and are restored as-is, exports are not encrypted
+func WithEncryption(kp KeyProvider) Option {
+	return func(o *options) {
+		o.kp = kp
+	}
+}
+
+// WithCompression will compress values at rest
+// Values are compressed with the provided compressor when transactions are committed and
+// when the log is archived. Each value is flagged, values which do not benefit from compression
+// are stored as-is. Values are decompressed when the database is loaded.
+// Compression can be enabled for an existing uncompressed database, values written before it was
+// enabled are read as-is and are compressed once the log is compacted.
+// Note: Compression cannot be disabled for a compressed database, ErrFormatMismatch is returned
+// instead. Use Export and Import to migrate.
+func WithCompression(c Compressor) Option {
+	return func(o *options) {
+		o.compressor = c
+	}
+}
+
+// WithChecksums will store a checksum with each record
+// A CRC32C is stored with every put and delete line when transactions are committed and when the
+// log is archived. Records which fail verification while loading are returned as a *CorruptionError.
+// Note: Every record holds a checksum, so checksums cannot be enabled for an existing database
+// without checksums (or disabled for one with checksums), ErrFormatMismatch is returned instead.
+// Use Export and Import to migrate.
+func WithChecksums() Option {
+	return func(o *options) {
+		o.checksums = true
+	}
+}
+
+// WithDurability will set when the log is synced to stable storage, see SetDurability
+func WithDurability(d Durability) Option {
+	return func(o *options) {
+		o.durability = d
+	}
+}
+
+// WithCompactionPolicy will set the automatic compaction policy, see SetCompactionPolicy
+func WithCompactionPolicy(p CompactionPolicy) Option {
+	return func(o *options) {
+		o.compaction = p
+	}
+}
+
+// WithMaxBatchSize will set the maximum number of calls within a batch, see SetMaxBatchSize
+func WithMaxBatchSize(n int) Option {
+	return func(o *options) {
+		o.maxBatchSize = n
+	}
+}
+
+// WithMaxBatchDelay will set the maximum delay before a batch is committed, see SetMaxBatchDelay
+func WithMaxBatchDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.maxBatchDelay = d
+	}
+}
+
+// newOptions will return the options with the provided options applied over the defaults
+func newOptions(opts []Option) (o options, err error) {
+	o.snapshotOnClose = true
+	o.maxBatchSize = DefaultMaxBatchSize
+	o.maxBatchDelay = DefaultMaxBatchDelay
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	err = o.validate()
+	return
+}
+
+// options are the options used to open a database
+type options struct {
+	// Codec for the value type, set as a codec[V]
+	codec any
+	// Logger, nil uses the standard logger
+	logger Logger
+	// Snapshot on close state
+	snapshotOnClose bool
+	// Read-only state
+	readOnly bool
+
+	// Bounded state and LRU cache size
+	bounded   bool
+	cacheSize int64
+
+	// Key provider, nil unless records are encrypted
+	kp KeyProvider
+	// Compressor, nil unless values are compressed
+	compressor Compressor
+	// Checksums state
+	checksums bool
+
+	durability Durability
+	compaction CompactionPolicy
+
+	maxBatchSize  int
+	maxBatchDelay time.Duration
+}
+
+// validate will ensure the options are valid before anything is opened
+func (o *options) validate() (err error) {
+	if o.bounded && o.cacheSize < 0 {
+		return ErrInvalidCacheSize
+	}
+
+	if o.bounded && o.readOnly {
+		// Bounded values are kept within a value file, which cannot be written
+		return ErrReadOnlyBounded
+	}
+
+	if o.durability.mode == syncInterval && o.durability.interval <= 0 {
+		return ErrInvalidDurability
+	}
+
+	if o.compaction.SizeRatio < 0 {
+		return ErrInvalidCompactionPolicy
+	}
+
+	if o.maxBatchSize <= 0 {
+		return ErrInvalidMaxBatchSize
+	}
+
+	if o.maxBatchDelay < 0 {
+		return ErrInvalidMaxBatchDelay
+	}
+
+	return
+}
+
+// codec is the marshal and unmarshal funcs for a value type
+type codec[V any] struct {
+	mfn MarshalFn[V]
+	ufn UnmarshalFn[V]
+}
+
+// logf will report an error encountered by a background goroutine
+func (t *Turtle[V]) logf(format string, v ...any) {
+	if t.logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+
+	t.logger.Printf(format, v...)
+}
@@ -0,0 +1,84 @@
+package turtle
+
+import (
+	"io"
+	"os"
+
+	"github.com/itsmontoya/mrT"
+)
+
+// OpenReadOnly will return a read-only instance of Turtle, see WithReadOnly
+func OpenReadOnly[V any](name, path string, ufn UnmarshalFn[V]) (tp *Turtle[V], err error) {
+	return Open[V](name, path, WithCodec[V](nil, ufn), WithReadOnly())
+}
+
+// openReadOnly will load the database from a private copy of the log
+// The back-end always opens it's log for writing (creating it when it does not exist), so it is
+// never opened within the database directory. This allows read-only files and directories to be read.
+func (t *Turtle[V]) openReadOnly(name, path string) (err error) {
+	if _, err = os.Stat(logFile(name, path)); err != nil {
+		// Avoid creating a log for a database which does not exist
+		return
+	}
+
+	var dir string
+	if dir, err = copyLog(name, path); err != nil {
+		return
+	}
+	// The copy is only needed while the database is loaded
+	defer os.RemoveAll(dir)
+
+	if t.mrT, err = mrT.New(dir, name); err != nil {
+		return
+	}
+
+	t.name = name
+	t.path = path
+	t.readOnly = true
+	t.watchers = make(map[*watcher[V]]struct{})
+	t.idxDefs = make(indexes[V])
+
+	if err = t.load(nil); err != nil {
+		t.mrT.Close()
+		return
+	}
+
+	// The loaded state is all we need, release the back-end
+	if err = t.mrT.Close(); err != nil {
+		return
+	}
+
+	t.mrT = nil
+	t.done = make(chan struct{})
+	return
+}
+
+// copyLog will copy the log of a database into a new temporary directory
+// The log is only opened for reading, the caller is responsible for removing the directory.
+func copyLog(name, path string) (dir string, err error) {
+	var src *os.File
+	if src, err = os.Open(logFile(name, path)); err != nil {
+		return
+	}
+	defer src.Close()
+
+	if dir, err = os.MkdirTemp("", name+".readonly.*"); err != nil {
+		return
+	}
+
+	var dst *os.File
+	if dst, err = os.Create(logFile(name, dir)); err == nil {
+		if _, err = io.Copy(dst, src); err != nil {
+			dst.Close()
+		} else {
+			err = dst.Close()
+		}
+	}
+
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return
+}
@@ -0,0 +1,197 @@
+package turtle
+
+import (
+	"os"
+
+	"github.com/itsmontoya/mrT"
+)
+
+// RepairOptions are the options used to repair a database
+// The key provider, compressor and checksums must match the options the database was created with,
+// ErrFormatMismatch is returned when they do not.
+type RepairOptions struct {
+	// Key provider, set when records are encrypted (see NewEncrypted)
+	Key KeyProvider
+	// Compressor, set when values are compressed (see NewCompressed)
+	Compressor Compressor
+	// Checksums state, set when records are checksummed (see NewChecksummed)
+	Checksums bool
+
+	// Quarantine state, when set discarded records are written as-is to a
+	// separate log named "<name>.quarantine" within the database directory
+	Quarantine bool
+}
+
+// RepairReport describes the records discarded by a repair
+type RepairReport struct {
+	// Number of records read from the log
+	Records uint64
+	// Records which could not be loaded and were discarded
+	Discarded []*CorruptionError
+	// Error encountered while reading the log, the remainder of the log was discarded
+	// This is generally caused by an incomplete trailing transaction
+	Truncated error
+	// Quarantine log name, empty unless discarded records were quarantined
+	Quarantine string
+}
+
+// Repaired will return whether or not the log was rewritten
+func (r *RepairReport) Repaired() bool {
+	return len(r.Discarded) > 0 || r.Truncated != nil
+}
+
+// Repair will salvage a database which cannot be loaded
+// The log is replayed with every record which cannot be read (or unmarshaled) discarded, as is the
+// remainder of the log once it can no longer be read. The recovered state is then archived through
+// the same path as a compaction, replacing the log. The log is left untouched when nothing is discarded.
+// Values are not unmarshaled, so records holding values which cannot be unmarshaled are kept.
+// Note: The database must not be open while it is being repaired
+func Repair(name, path string, opts RepairOptions) (rp *RepairReport, err error) {
+	if _, err = os.Stat(path); err != nil {
+		// Avoid creating a database directory for a database which does not exist
+		return
+	}
+
+	var t Turtle[[]byte]
+	t.name = name
+	t.path = path
+	t.mfn = rawMarshal
+	t.ufn = rawUnmarshal
+	t.watchers = make(map[*watcher[[]byte]]struct{})
+	t.idxDefs = make(indexes[[]byte])
+
+	if opts.Key != nil {
+		if t.c, err = newRecordCipher(opts.Key); err != nil {
+			return
+		}
+	}
+
+	if opts.Compressor != nil {
+		t.z = &recordCompressor{c: opts.Compressor}
+	}
+
+	if opts.Checksums {
+		t.sum = &recordChecksum{}
+	}
+
+	if t.mrT, err = mrT.New(path, name); err != nil {
+		return
+	}
+	defer t.mrT.Close()
+
+	var (
+		r RepairReport
+		// Discarded records, written to the quarantine log
+		q []quarantined
+	)
+
+	if err = t.load(func(record int64, lineType byte, bkey, value []byte, err error) error {
+		if lineType == mrT.NilLine {
+			// Remainder of the log cannot be read
+			r.Truncated = err
+			return nil
+		}
+
+		cerr, ok := err.(*CorruptionError)
+		switch {
+		case ok:
+		case err == ErrDecrypt:
+			// Decryption errors are not corruption errors when loading, as they are generally
+			// caused by an incorrect key. Repair treats them as damaged records.
+			cerr = newCorruptionError(name, path, record, bkey, err)
+		default:
+			// Not a problem with the record, return the error as-is
+			return err
+		}
+
+		r.Discarded = append(r.Discarded, cerr)
+		if opts.Quarantine {
+			q = append(q, newQuarantined(lineType, bkey, value))
+		}
+
+		return nil
+	}); err != nil {
+		return
+	}
+
+	r.Records = t.logRecords
+	if !r.Repaired() {
+		// Nothing was discarded, leave the log as-is
+		return &r, nil
+	}
+
+	if st := t.st.Load(); st.s.len == 0 && len(st.b) == 0 {
+		// No record could be read, this is generally caused by the wrong key
+		// Return before the log is replaced with an empty one
+		return &r, ErrNothingRecovered
+	}
+
+	if len(q) > 0 {
+		r.Quarantine = name + ".quarantine"
+		if err = quarantine(path, r.Quarantine, q); err != nil {
+			return
+		}
+	}
+
+	if err = t.snapshot().Err(); err != nil {
+		return
+	}
+
+	rp = &r
+	return
+}
+
+// newQuarantined will return a copy of a discarded record
+func newQuarantined(lineType byte, bkey, value []byte) (q quarantined) {
+	q.lineType = lineType
+	q.key = append([]byte(nil), bkey...)
+	q.value = append([]byte(nil), value...)
+	return
+}
+
+// quarantined is a discarded record, as it was stored
+type quarantined struct {
+	lineType byte
+	key      []byte
+	value    []byte
+}
+
+// quarantine will append discarded records to a quarantine log within a single transaction
+func quarantine(path, name string, q []quarantined) (err error) {
+	var m *mrT.MrT
+	if m, err = mrT.New(path, name); err != nil {
+		return
+	}
+
+	if err = m.Txn(func(txn *mrT.Txn) (err error) {
+		for _, r := range q {
+			if r.lineType == mrT.DeleteLine {
+				err = txn.Delete(r.key)
+			} else {
+				err = txn.Put(r.key, r.value)
+			}
+
+			if err != nil {
+				return
+			}
+		}
+
+		return
+	}); err != nil {
+		m.Close()
+		return
+	}
+
+	return m.Close()
+}
+
+// rawMarshal is the MarshalFn used while repairing, values are kept as their marshaled bytes
+func rawMarshal(b []byte) ([]byte, error) {
+	return b, nil
+}
+
+// rawUnmarshal is the UnmarshalFn used while repairing, values are kept as their marshaled bytes
+func rawUnmarshal(b []byte) ([]byte, error) {
+	// Copy the bytes, the back-end may re-use it's buffers
+	return append([]byte(nil), b...), nil
+}
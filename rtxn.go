@@ -1,40 +1,49 @@
 package turtle
 
 // RTxn is a read transaction
-type RTxn struct {
-	// Original store
-	s store
+type RTxn[V any] struct {
+	// Root bucket
+	rbucket[V]
+	// Original buckets
+	b buckets[V]
+	// Original secondary indexes
+	idx indexes[V]
 }
 
-func (r *RTxn) clear() {
-	r.s = nil
+func (r *RTxn[V]) clear() {
+	r.s = store[V]{}
+	r.b = nil
+	r.idx = nil
 }
 
-// Get will get a value for a provided key
-func (r *RTxn) Get(key string) (Value, error) {
-	return r.s.get(key)
+// Index will return the secondary index matching the provided name
+func (r *RTxn[V]) Index(name string) *Index[V] {
+	return newIndex(r.idx, name, nil, r.Get)
 }
 
-// Put will put a value for a provided key
-func (r *RTxn) Put(key string, value Value) error {
-	// Cannot perform PUT actions during a read transaction
-	return ErrNotWriteTxn
-}
+// Bucket will return the bucket matching the provided name
+func (r *RTxn[V]) Bucket(name string) (bucket Bucket[V], err error) {
+	var (
+		s  store[V]
+		ok bool
+	)
 
-// Delete will delete a key
-func (r *RTxn) Delete(key string) error {
-	// Cannot perform PUT actions during a read transaction
-	return ErrNotWriteTxn
+	if s, ok = r.b[name]; !ok {
+		// Bucket does not exist, return early with error
+		return nil, ErrBucketDoesNotExist
+	}
+
+	return &rbucket[V]{s: s}, nil
 }
 
-// ForEach will iterate through all current items
-func (r *RTxn) ForEach(fn ForEachFn) (err error) {
-	for key, value := range r.s {
-		if fn(key, value) {
-			// End was called, return early
-			return
-		}
-	}
+// CreateBucket will create a bucket for the provided name
+func (r *RTxn[V]) CreateBucket(name string) (Bucket[V], error) {
+	// Cannot perform CREATE actions during a read transaction
+	return nil, ErrNotWriteTxn
+}
 
-	return
+// DeleteBucket will delete the bucket matching the provided name
+func (r *RTxn[V]) DeleteBucket(name string) error {
+	// Cannot perform DELETE actions during a read transaction
+	return ErrNotWriteTxn
 }
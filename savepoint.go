@@ -0,0 +1,188 @@
+package turtle
+
+// Savepoint is a point within a write transaction which can be rolled back to
+type Savepoint[V any] struct {
+	// Parent transaction
+	w *WTxn[V]
+	// Unique ID of the savepoint within the parent transaction, IDs are never re-used
+	id uint64
+	// Length of the parent transaction undo journal when the savepoint was created
+	pos int
+}
+
+// undo is a single change to a write transaction, recorded while savepoints are live
+// Changes are undone in reverse order when rolling back to a savepoint, so a savepoint
+// costs nothing to create and rolling back only touches the changes made since.
+type undo[V any] struct {
+	// Transaction store of the changed key, nil for bucket changes
+	ts txnStore[V]
+	// Changed key, or the changed bucket name for bucket changes
+	key string
+	// Root state, true when the key belongs to the root bucket
+	root bool
+	// Previous action for the key, nil if the key had no action
+	a *action[V]
+
+	// Previous bucket action, nil if the bucket had no action
+	ba *bucketAction[V]
+	// Previous bucket action fields
+	state bucketAction[V]
+}
+
+// copy will return a shallow copy of a transaction store
+// Actions are replaced rather than modified, so they can safely be shared
+func (t txnStore[V]) copy() (out txnStore[V]) {
+	if t == nil {
+		return
+	}
+
+	out = make(txnStore[V], len(t))
+	for key, action := range t {
+		out[key] = action
+	}
+
+	return
+}
+
+// recordKey will record the current action for a key of a bucket before it is replaced
+// Root keys are marked as changed for the indexes, nothing is recorded unless a savepoint is live
+func (w *WTxn[V]) recordKey(b *wbucket[V], key string) {
+	root := b == &w.wbucket
+	if root {
+		w.markDirty(key)
+	}
+
+	if len(w.sps) == 0 {
+		return
+	}
+
+	w.undos = append(w.undos, undo[V]{ts: b.ts, key: key, root: root, a: b.ts[key]})
+}
+
+// recordBucket will record the current action for a bucket before it is modified
+// Nothing is recorded unless a savepoint is live
+func (w *WTxn[V]) recordBucket(name string) {
+	if len(w.sps) == 0 {
+		return
+	}
+
+	u := undo[V]{key: name}
+	if ba, ok := w.tb[name]; ok {
+		u.ba = ba
+		u.state = *ba
+	}
+
+	w.undos = append(w.undos, u)
+}
+
+// Savepoint will create a savepoint for the current state of the transaction
+func (w *WTxn[V]) Savepoint() *Savepoint[V] {
+	var sp Savepoint[V]
+	w.spSeq++
+	sp.w = w
+	sp.id = w.spSeq
+	sp.pos = len(w.undos)
+	w.sps = append(w.sps, &sp)
+	return &sp
+}
+
+// savepointIndex will return the position of a savepoint within the live savepoints
+// A value of -1 is returned if the savepoint does not belong to this transaction or has been invalidated
+func (w *WTxn[V]) savepointIndex(sp *Savepoint[V]) int {
+	if sp == nil || sp.w != w {
+		return -1
+	}
+
+	for i := len(w.sps) - 1; i >= 0; i-- {
+		if w.sps[i].id == sp.id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// RollbackTo will discard all actions taken since the provided savepoint was created
+// The savepoint remains valid, while any savepoints created after it are invalidated.
+// Cursors and indexes capture the pending actions when they are retrieved, so they are not affected.
+// Note: Buckets retrieved after the savepoint was created should be retrieved again.
+func (w *WTxn[V]) RollbackTo(sp *Savepoint[V]) (err error) {
+	i := w.savepointIndex(sp)
+	if i == -1 {
+		// Savepoint does not belong to this transaction or has been invalidated
+		return ErrInvalidSavepoint
+	}
+
+	// Undo changes in reverse order
+	for j := len(w.undos) - 1; j >= sp.pos; j-- {
+		u := w.undos[j]
+		switch {
+		case u.ts != nil && u.a == nil:
+			// Key had no action, remove it
+			delete(u.ts, u.key)
+		case u.ts != nil:
+			// Restore previous action for the key
+			u.ts[u.key] = u.a
+		case u.ba == nil:
+			// Bucket action was taken after the change, remove it
+			delete(w.tb, u.key)
+		default:
+			// Restore bucket action fields, this also restores the original transaction store reference
+			*u.ba = u.state
+			w.tb[u.key] = u.ba
+		}
+
+		if u.root {
+			// Restored key must be checked against the indexes again
+			w.markDirty(u.key)
+		}
+
+		// Release references held by the journal
+		w.undos[j] = undo[V]{}
+	}
+
+	w.undos = w.undos[:sp.pos]
+	// Invalidate any savepoints created after this one
+	w.sps = w.sps[:i+1]
+	return
+}
+
+// release will invalidate a savepoint and any savepoints created after it, keeping their changes
+// The undo journal is emptied once no savepoints are live.
+func (w *WTxn[V]) release(sp *Savepoint[V]) {
+	i := w.savepointIndex(sp)
+	if i == -1 {
+		return
+	}
+
+	if w.sps = w.sps[:i]; len(w.sps) > 0 {
+		// Changes are still needed by an earlier savepoint
+		return
+	}
+
+	for j := range w.undos {
+		// Release references held by the journal
+		w.undos[j] = undo[V]{}
+	}
+
+	w.undos = w.undos[:0]
+}
+
+// Nested will call the provided func as a sub-transaction
+// If the func returns an error, all actions taken within the func are rolled back
+// and the error is returned. The parent transaction remains usable.
+// Note: Savepoints created within the func are invalidated once it returns
+func (w *WTxn[V]) Nested(fn TxnFn[V]) (err error) {
+	sp := w.Savepoint()
+	if err = fn(w); err == nil {
+		// Actions are kept, the savepoint is no longer needed
+		w.release(sp)
+		return
+	}
+
+	// Error encountered, rollback to the savepoint and release it
+	// Note: Rolling back to a savepoint owned by this transaction cannot fail
+	w.RollbackTo(sp)
+	w.release(sp)
+	return
+}
@@ -0,0 +1,43 @@
+package turtle
+
+import "sync/atomic"
+
+// Stats are the statistics of a database
+type Stats struct {
+	// Number of root keys
+	Keys int
+	// Number of buckets
+	Buckets int
+	// Number of keys within buckets
+	BucketKeys int
+	// Number of keys with an expiry, including expired keys which have not been removed
+	Expiring int
+	// Number of secondary indexes
+	Indexes int
+	// Number of records within the log
+	LogRecords uint64
+	// Size of the records within the log (see CompactionPolicy.SizeRatio)
+	LogSize uint64
+	// Size of the records holding the current value of each key
+	LiveSize uint64
+	// Number of transactions committed since the last compaction
+	Transactions uint64
+}
+
+// Stats will return the current statistics of the database
+func (t *Turtle[V]) Stats() (s Stats) {
+	st := t.st.Load()
+	s.Keys = st.s.len
+	s.Buckets = len(st.b)
+	for _, bs := range st.b {
+		s.BucketKeys += bs.len
+	}
+
+	s.Expiring = st.ttl.len
+	s.Indexes = len(st.idx)
+	s.LogRecords = atomic.LoadUint64(&t.logRecords)
+	s.LogSize = atomic.LoadUint64(&t.logSize)
+	s.LiveSize = uint64(st.size())
+	s.Transactions = atomic.LoadUint64(&t.txns)
+	return
+}
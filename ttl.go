@@ -0,0 +1,135 @@
+package turtle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ttlPrefix is the back-end key prefix for expiry lines
+	// Bucket names cannot be empty, so this will never collide with a bucket key
+	ttlPrefix = bucketSep + bucketSep
+
+	// DefaultReapInterval is the default interval at which expired keys are removed
+	DefaultReapInterval = time.Second
+)
+
+// isTTLKey will return whether or not a back-end key belongs to an expiry line
+func isTTLKey(bkey string) bool {
+	return strings.HasPrefix(bkey, ttlPrefix)
+}
+
+// getTTLKey will return the back-end key of the expiry line for a back-end key
+func getTTLKey(bkey string) string {
+	return ttlPrefix + bkey
+}
+
+// encodeExpiry will encode an expiry as bytes
+func encodeExpiry(expires int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(expires))
+	return b
+}
+
+// decodeExpiry will decode an expiry from bytes
+func decodeExpiry(b []byte) (expires int64, err error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("invalid expiry length, expected 8 and received %d", len(b))
+	}
+
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// getExpiryKey will return the expiry index key for a key within a bucket
+// Expiries are hex encoded with a fixed width so the index is ordered by expiry
+func getExpiryKey(expires int64, name, key string) string {
+	return fmt.Sprintf("%016x", uint64(expires)) + name + bucketSep + key
+}
+
+// parseExpiryKey will parse an expiry index key into it's expiry, bucket name and key
+func parseExpiryKey(ekey string) (expires int64, name, key string) {
+	ts, _ := strconv.ParseUint(ekey[:16], 16, 64)
+	ekey = ekey[16:]
+	idx := strings.Index(ekey, bucketSep)
+	return int64(ts), ekey[:idx], ekey[idx+len(bucketSep):]
+}
+
+// loadExpiry will set the expiry for a key from an expiry line
+func (st *state[V]) loadExpiry(tkey string, value []byte) (err error) {
+	var expires int64
+	if expires, err = decodeExpiry(value); err != nil {
+		return
+	}
+
+	name, key, _ := parseKey(tkey[len(ttlPrefix):])
+	n := st.bucket(name).root.get(key)
+	if n == nil {
+		// Key does not exist, nothing to expire
+		return
+	}
+
+	st.put(name, key, n.value, n.ref, expires, n.size)
+	return
+}
+
+// reaper will periodically remove expired keys until the database is closed
+func (t *Turtle[V]) reaper(interval time.Duration) {
+	tkr := time.NewTicker(interval)
+	defer tkr.Stop()
+
+	for {
+		select {
+		case <-tkr.C:
+		case <-t.done:
+			return
+		}
+
+		if err := t.reap(); err != nil && !t.isClosed() {
+			t.logf("turtle: error encountered while removing expired keys: %v", err)
+		}
+	}
+}
+
+// reap will remove all keys which have expired
+func (t *Turtle[V]) reap() (err error) {
+	now := time.Now().UnixNano()
+	if n := t.st.Load().ttl.root.first(); n == nil || !isExpiryKeyDue(n.key, now) {
+		// No keys have expired, avoid acquiring the write-lock
+		return
+	}
+
+	return t.Update(func(txn Txn[V]) (err error) {
+		w := txn.(*WTxn[V])
+		w.ttl.root.walk(func(n *node[struct{}]) (end bool) {
+			if !isExpiryKeyDue(n.key, now) {
+				// We've reached the unexpired keys, end early
+				return true
+			}
+
+			_, name, key := parseExpiryKey(n.key)
+			if len(name) == 0 {
+				err = w.Delete(key)
+				return err != nil
+			}
+
+			var bkt Bucket[V]
+			if bkt, err = w.Bucket(name); err != nil {
+				return true
+			}
+
+			err = bkt.Delete(key)
+			return err != nil
+		})
+
+		return
+	})
+}
+
+// isExpiryKeyDue will return whether or not an expiry index key has expired as of the provided time
+func isExpiryKeyDue(ekey string, now int64) bool {
+	expires, _, _ := parseExpiryKey(ekey)
+	return expires <= now
+}
@@ -3,8 +3,8 @@ package turtle
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
-	"github.com/cheekybits/genny/generic"
 	"github.com/itsmontoya/mrT"
 	"github.com/missionMeteora/toolkit/errors"
 )
@@ -14,23 +14,152 @@ const (
 	ErrNotWriteTxn = errors.Error("cannot perform write actions during a read transaction")
 	// ErrKeyDoesNotExist is returned when a key does not exist
 	ErrKeyDoesNotExist = errors.Error("key does not exist")
+	// ErrInvalidKey is returned when a key begins with a null byte
+	ErrInvalidKey = errors.Error("invalid key, keys cannot begin with a null byte")
+	// ErrBucketDoesNotExist is returned when a bucket does not exist
+	ErrBucketDoesNotExist = errors.Error("bucket does not exist")
+	// ErrBucketExists is returned when creating a bucket which already exists
+	ErrBucketExists = errors.Error("bucket already exists")
+	// ErrInvalidBucketName is returned when a bucket name is empty or contains a null byte
+	ErrInvalidBucketName = errors.Error("invalid bucket name, names must be non-empty and cannot contain a null byte")
+	// ErrTxDone is returned when a transaction is used after Commit or Rollback have been called
+	ErrTxDone = errors.Error("transaction has already been committed or rolled back")
+	// ErrInvalidSavepoint is returned when rolling back to a savepoint which is not valid for a transaction
+	ErrInvalidSavepoint = errors.Error("invalid savepoint")
+	// ErrInvalidTTL is returned when a TTL is not greater than zero
+	ErrInvalidTTL = errors.Error("invalid TTL, TTLs must be greater than zero")
+	// ErrIndexDoesNotExist is returned when an index does not exist
+	ErrIndexDoesNotExist = errors.Error("index does not exist")
+	// ErrIndexExists is returned when creating an index which already exists
+	ErrIndexExists = errors.Error("index already exists")
+	// ErrInvalidIndexKey is returned when an index func returns an index key containing a null byte
+	ErrInvalidIndexKey = errors.Error("invalid index key, index keys cannot contain a null byte")
+	// ErrInvalidCompactionPolicy is returned when a compaction policy has a negative size ratio
+	ErrInvalidCompactionPolicy = errors.Error("invalid compaction policy, size ratio cannot be negative")
+	// ErrInvalidBackup is returned when restoring from a backup which is malformed or truncated
+	ErrInvalidBackup = errors.Error("invalid backup")
+	// ErrDatabaseExists is returned when restoring to a database which already contains data
+	ErrDatabaseExists = errors.Error("database already exists")
+	// ErrInvalidFormat is returned when an export format is not supported or an import is malformed
+	ErrInvalidFormat = errors.Error("invalid format")
+	// ErrReadOnly is returned when write actions are performed on a read-only database
+	ErrReadOnly = errors.Error("cannot perform write actions on a read-only database")
+	// ErrNoMarshalFn is returned when values need to be marshaled by a database without a MarshalFn
+	ErrNoMarshalFn = errors.Error("cannot marshal values, no marshal func was provided")
+	// ErrInvalidCacheSize is returned when a cache size is negative
+	ErrInvalidCacheSize = errors.Error("invalid cache size, cache size cannot be negative")
+	// ErrReadOnlyBounded is returned when a read-only database is opened with bounded values
+	ErrReadOnlyBounded = errors.Error("read-only databases cannot keep values on disk")
+	// ErrInvalidEncryptionKey is returned when an encryption key is not 32 bytes
+	ErrInvalidEncryptionKey = errors.Error("invalid encryption key, keys must be 32 bytes")
+	// ErrDecrypt is returned when a record cannot be decrypted, the key is incorrect or the record was modified
+	ErrDecrypt = errors.Error("unable to decrypt record, the encryption key is incorrect or the record has been tampered with")
+	// ErrInvalidKeyID is returned when an encryption key ID is longer than 255 bytes
+	ErrInvalidKeyID = errors.Error("invalid encryption key ID, key IDs cannot be longer than 255 bytes")
+	// ErrKeyNotFound is returned when a key provider does not have a key for a key ID
+	ErrKeyNotFound = errors.Error("encryption key not found")
+	// ErrKeyIsActive is returned when removing the active key from a key ring
+	ErrKeyIsActive = errors.Error("cannot remove the active encryption key")
+	// ErrNotEncrypted is returned when re-keying a database which is not encrypted
+	ErrNotEncrypted = errors.Error("database is not encrypted")
+	// ErrInvalidCompressionFlag is returned when a record value has an unknown compression flag
+	ErrInvalidCompressionFlag = errors.Error("invalid compression flag")
+	// ErrChecksumMismatch is returned when a record does not match it's checksum
+	ErrChecksumMismatch = errors.Error("record checksum mismatch")
+	// ErrNothingRecovered is returned when a repair cannot read any of the records within a log
+	ErrNothingRecovered = errors.Error("no records could be recovered")
+	// ErrInvalidDurability is returned when a sync interval is not positive
+	ErrInvalidDurability = errors.Error("invalid durability, sync interval must be positive")
+	// ErrInvalidCodec is returned when opening a database without a codec for it's value type
+	ErrInvalidCodec = errors.Error("invalid codec, a codec for the value type must be provided")
+	// ErrInvalidMaxBatchSize is returned when a max batch size is not positive
+	ErrInvalidMaxBatchSize = errors.Error("invalid max batch size, size must be positive")
+	// ErrInvalidMaxBatchDelay is returned when a max batch delay is negative
+	ErrInvalidMaxBatchDelay = errors.Error("invalid max batch delay, delay cannot be negative")
+	// ErrFormatMismatch is returned when a log holds records which cannot be read with the provided options
+	// A database must be opened with the same checksum, compression and encryption options it was created with.
+	ErrFormatMismatch = errors.Error("format mismatch, records do not match the checksum, compression or encryption options")
 )
 
-// Value is the value type
-type Value generic.Type
-
 // New will return a new instance of Turtle
-func New(name, path string, mfn MarshalFn, ufn UnmarshalFn) (tp *Turtle, err error) {
-	var t Turtle
-	if t.mrT, err = mrT.New(path, name); err != nil {
+// Any provided options are applied after the codec, see Open.
+func New[V any](name, path string, mfn MarshalFn[V], ufn UnmarshalFn[V], opts ...Option) (tp *Turtle[V], err error) {
+	return Open[V](name, path, append([]Option{WithCodec(mfn, ufn)}, opts...)...)
+}
+
+// NewBounded will return a new instance of Turtle which keeps values on disk, see WithBounded
+func NewBounded[V any](name, path string, mfn MarshalFn[V], ufn UnmarshalFn[V], cacheSize int64) (tp *Turtle[V], err error) {
+	return New(name, path, mfn, ufn, WithBounded(cacheSize))
+}
+
+// NewEncrypted will return a new instance of Turtle which encrypts records at rest, see WithEncryption
+func NewEncrypted[V any](name, path string, mfn MarshalFn[V], ufn UnmarshalFn[V], kp KeyProvider) (tp *Turtle[V], err error) {
+	return New(name, path, mfn, ufn, WithEncryption(kp))
+}
+
+// NewCompressed will return a new instance of Turtle which compresses values at rest, see WithCompression
+func NewCompressed[V any](name, path string, mfn MarshalFn[V], ufn UnmarshalFn[V], c Compressor) (tp *Turtle[V], err error) {
+	return New(name, path, mfn, ufn, WithCompression(c))
+}
+
+// NewChecksummed will return a new instance of Turtle which stores a checksum with each record, see WithChecksums
+func NewChecksummed[V any](name, path string, mfn MarshalFn[V], ufn UnmarshalFn[V]) (tp *Turtle[V], err error) {
+	return New(name, path, mfn, ufn, WithChecksums())
+}
+
+// Open will return a new instance of Turtle configured by the provided options
+// A codec for the value type is required (see WithCodec), every other option is optional.
+func Open[V any](name, path string, opts ...Option) (tp *Turtle[V], err error) {
+	var o options
+	if o, err = newOptions(opts); err != nil {
+		return
+	}
+
+	c, ok := o.codec.(codec[V])
+	if !ok {
+		// Codec was not provided, or was provided for another value type
+		return nil, ErrInvalidCodec
+	}
+
+	var t Turtle[V]
+	t.mfn = c.mfn
+	t.ufn = c.ufn
+	t.logger = o.logger
+	t.skipSnapshot = !o.snapshotOnClose
+	if o.kp != nil {
+		if t.c, err = newRecordCipher(o.kp); err != nil {
+			return
+		}
+	}
+
+	if o.compressor != nil {
+		t.z = &recordCompressor{c: o.compressor}
+	}
+
+	if o.checksums {
+		t.sum = &recordChecksum{}
+	}
+
+	if o.readOnly {
+		if err = t.openReadOnly(name, path); err != nil {
+			return
+		}
+
+		tp = &t
 		return
 	}
 
-	t.s = make(store)
-	t.mfn = mfn
-	t.ufn = ufn
+	if err = t.open(name, path, o); err != nil {
+		return
+	}
+
+	// Options were validated, so neither of these are expected to fail
+	if err = t.SetDurability(o.durability); err == nil {
+		err = t.SetCompactionPolicy(o.compaction)
+	}
 
-	if err = t.load(); err != nil {
+	if err != nil {
+		t.Close()
 		return
 	}
 
@@ -38,123 +167,572 @@ func New(name, path string, mfn MarshalFn, ufn UnmarshalFn) (tp *Turtle, err err
 	return
 }
 
+// open will open the back-end, load the database and start the background goroutines
+func (t *Turtle[V]) open(name, path string, o options) (err error) {
+	if t.mrT, err = mrT.New(path, name); err != nil {
+		return
+	}
+
+	if o.bounded {
+		// Value file is kept alongside the log, it is opened once the log has been opened
+		if t.vs, err = newValues(path, name, t.ufn, o.cacheSize, t.c); err != nil {
+			t.mrT.Close()
+			return
+		}
+	}
+
+	t.name = name
+	t.path = path
+	t.syncFn = syncFile
+	t.maxBatchSize = o.maxBatchSize
+	t.maxBatchDelay = o.maxBatchDelay
+	t.watchers = make(map[*watcher[V]]struct{})
+	t.idxDefs = make(indexes[V])
+
+	if err = t.load(nil); err != nil {
+		// Release the back-end, the load error takes precedence
+		t.mrT.Close()
+		if t.vs != nil {
+			t.vs.close()
+		}
+
+		return
+	}
+
+	if t.vs != nil {
+		// The value file index is only valid while the log is loaded
+		t.vs.idx = nil
+	}
+
+	t.done = make(chan struct{})
+	t.compactSignal = make(chan struct{}, 1)
+	// Start removing expired keys in the background
+	go t.reaper(DefaultReapInterval)
+	return
+}
+
 // Turtle is a DB, he's not a slow fella - I promise!
-type Turtle struct {
-	// Read/Write mutex
-	mux sync.RWMutex
+type Turtle[V any] struct {
+	// Write mutex, readers never acquire this
+	mux sync.Mutex
 	// Back-end persistence
 	mrT *mrT.MrT
-	// Internal store
-	s store
+	// Current state, replaced atomically on each successful update
+	st atomic.Pointer[state[V]]
+
+	mfn MarshalFn[V]
+	ufn UnmarshalFn[V]
+
+	// Logger, nil uses the standard logger (see WithLogger)
+	logger Logger
+	// Skip snapshot state, set when the log is not replaced on close (see WithSnapshotOnClose)
+	skipSnapshot bool
+
+	// Value file, nil unless values are disk-resident (see NewBounded)
+	vs *values[V]
+	// Record cipher, nil unless records are encrypted (see NewEncrypted)
+	c *recordCipher
+	// Record compressor, nil unless values are compressed (see NewCompressed)
+	z *recordCompressor
+	// Record checksum, nil unless records are checksummed (see NewChecksummed)
+	sum *recordChecksum
+
+	// Database name and path, used to report corrupt records
+	name string
+	path string
+
+	// Secondary index definitions, used to rebuild indexes on load
+	idxDefs indexes[V]
 
-	mfn MarshalFn
-	ufn UnmarshalFn
+	// Batch mutex
+	batchMux sync.Mutex
+	// Current batch
+	batch *batch[V]
+	// Maximum number of calls within a batch
+	maxBatchSize int
+	// Maximum delay before a batch is committed
+	maxBatchDelay time.Duration
 
+	// Read-only state, set when the database is opened with OpenReadOnly
+	readOnly bool
 	// Closed state
 	closed uint32
+	// Closed channel, used to stop background goroutines
+	done chan struct{}
+
+	// Compaction mutex
+	compactMux sync.Mutex
+	// Compactor stop channel, nil when no compactor is running
+	compactStop chan struct{}
+	// Compactor signal channel, notified after each commit
+	compactSignal chan struct{}
+	// Number of records within the log, updated atomically
+	logRecords uint64
+	// Size of the records within the log, updated atomically
+	logSize uint64
+	// Archive mutex, held while the log is replaced so that a single snapshot is written at a time
+	archiveMux sync.Mutex
+	// Journal of the transactions committed while a snapshot is written, nil unless compacting
+	// Only accessed while the write-lock is held
+	journal *journal
+	// Number of transactions committed since the last snapshot, updated atomically
+	txns uint64
+
+	// Durability, only accessed while the write-lock is held
+	durability Durability
+	// Unsynced state, set when transactions have been committed since the last sync
+	unsynced bool
+	// Sync func used to sync the log and database directory, syncFile unless replaced by tests
+	syncFn func(filename string) error
+	// Formatted state, set when the log describes the records written by the database
+	// Only accessed while the write-lock is held
+	formatted bool
+	// Syncer stop channel, nil when no interval syncer is running
+	syncStop chan struct{}
+
+	// Watchers mutex
+	watchMux sync.RWMutex
+	// Active watchers
+	watchers map[*watcher[V]]struct{}
+	// Commit sequence, incremented for each successful commit
+	seq uint64
 }
 
 // isClosed will atomically check the closed state of the database
-func (t *Turtle) isClosed() bool {
+func (t *Turtle[V]) isClosed() bool {
 	return atomic.LoadUint32(&t.closed) == 1
 }
 
 // load is called on DB initialization and will populate the in-memory store from our file back-end
-func (t *Turtle) load() (err error) {
+// Records which cannot be loaded are passed to discard when it is not nil (see Repair)
+func (t *Turtle[V]) load(discard discardFn) (err error) {
 	// Inner error, this is intended so that the error returned by ForEach
 	// does not overwrite a true error we encounter during iteration.
 	// To explain further - if ForEach returns a nil error, yet we encountered
 	// an unmarshal error during the loop. The error would be returned as nil.
 	var ierr error
-	if err = t.mrT.ForEach(func(lineType byte, key, value []byte) (end bool) {
-		if lineType == mrT.DeleteLine {
-			// We encountered a delete line, remove the key from the map and return early
-			delete(t.s, string(key))
-			return
+	// Position of the current record within the log
+	var record int64
+	// State being loaded
+	st := state[V]{b: make(buckets[V])}
+	// Format of the records being loaded, logs without a format header hold records stored as-is
+	var f format
+	if err = t.mrT.ForEach(func(lineType byte, bkey, value []byte) (end bool) {
+		// Count record for compaction
+		t.logRecords++
+		t.logSize += uint64(recordSize(bkey, value))
+		pos := record
+		record++
+		if isFormatKey(bkey) {
+			// Format header, the records which follow are stored in the described format
+			// Note: Format mismatches are not corruption, so they cannot be discarded
+			if f, ierr = decodeFormat(value); ierr == nil {
+				ierr = t.checkFormat(f)
+			}
+
+			return ierr != nil
 		}
 
-		var v Value
-		if v, ierr = t.ufn(value); err != nil {
-			// Error encountered while unmarshaling, return and end the loop early
-			return true
+		if pos == 0 {
+			// Log does not have a format header, ensure the records can be read before loading them
+			if ierr = t.checkFormat(f); ierr != nil {
+				return true
+			}
 		}
 
-		// Set the key as our parsed value within the database store
-		t.s[string(key)] = v
-		return
+		if ierr = t.loadRecord(&st, f, pos, lineType, bkey, value); ierr != nil && discard != nil {
+			// Allow the record to be skipped
+			ierr = discard(pos, lineType, bkey, value, ierr)
+		}
+
+		return ierr != nil
 	}); err != nil {
 		// Error encountered during ForEach, generally a disk or middleware related issue
 		// Any error which may be encountered SHOULD occur before any iteration occurs
 		// TODO: Do some heavy combing through the codebase to confirm this statement
+		if discard == nil {
+			return
+		}
+
+		// Allow the remainder of the log to be skipped, keeping the records loaded before it
+		if err = discard(record, mrT.NilLine, nil, nil, err); err != nil {
+			return
+		}
+	}
+
+	if ierr != nil {
+		// Return any inner errors encountered
+		return ierr
+	}
+
+	// Rebuild secondary indexes from the loaded store
+	st.idx = make(indexes[V], len(t.idxDefs))
+	for name, def := range t.idxDefs {
+		// Note: Index keys which cannot be encoded are skipped, they were rejected when written
+		st.idx[name], _ = st.buildIndex(def)
+	}
+
+	// Set the loaded state as the current state
+	t.st.Store(&st)
+	// A format header is written with the next commit when the log is empty, or when the
+	// records at the end of the log are stored in a different format (see checkFormat)
+	t.formatted = record > 0 && f == t.getFormat()
+	return
+}
+
+// loadRecord will apply a single record stored in the provided format to the state being loaded
+// Records which cannot be read are returned as a *CorruptionError
+func (t *Turtle[V]) loadRecord(st *state[V], f format, record int64, lineType byte, bkey, value []byte) (err error) {
+	// Size of the record as it is stored within the log
+	size := recordSize(bkey, value)
+	// Verify line when records are checksummed
+	if bkey, err = t.sum.verify(bkey, value); err != nil {
+		return newCorruptionError(t.name, t.path, record, bkey, err)
+	}
+
+	// Open line when records are encrypted
+	// Note: Decryption errors are returned as-is, as they are generally caused by an incorrect key
+	if bkey, value, err = t.c.open(bkey, value); err != nil {
 		return
 	}
 
-	// Return any inner errors encountered
-	return ierr
+	if lineType != mrT.DeleteLine && f&formatCompressed != 0 {
+		// Decode value when values are compressed
+		if value, err = t.z.decode(value); err != nil {
+			return newCorruptionError(t.name, t.path, record, bkey, err)
+		}
+	}
+
+	if isTTLKey(string(bkey)) {
+		// We encountered an expiry line, set the expiry for the preceding put and return early
+		if err = st.loadExpiry(string(bkey), value); err != nil {
+			return newCorruptionError(t.name, t.path, record, bkey, err)
+		}
+
+		return
+	}
+
+	name, key, isMarker := parseKey(string(bkey))
+	if isMarker {
+		// We encountered a bucket marker, create or remove the bucket and return early
+		if lineType == mrT.DeleteLine {
+			st.deleteBucket(name)
+		} else if !st.b.exists(name) {
+			st.b[name] = store[V]{}
+		}
+
+		return
+	}
+
+	if lineType == mrT.DeleteLine {
+		// We encountered a delete line, remove the key from the store and return early
+		// Note: Buckets without an encountered marker will start with an empty store
+		st.delete(name, key)
+		return
+	}
+
+	var v V
+	if t.vs != nil {
+		// Values are disk-resident, write the value to the value file rather than unmarshaling
+		// Values which are already held by the value file (see newValues) are not written again
+		ref, ok := t.vs.lookup(name, key)
+		if !ok {
+			if ref, err = t.vs.append(value); err != nil {
+				return
+			}
+		}
+
+		st.put(name, key, v, ref, 0, size)
+		return
+	}
+
+	if v, err = t.ufn(value); err != nil {
+		// Error encountered while unmarshaling, the value is not what was marshaled
+		return newCorruptionError(t.name, t.path, record, bkey, err)
+	}
+
+	// Set the key as our parsed value within the database store
+	// Note: Any expiry is set by the expiry line which directly follows
+	st.put(name, key, v, nil, 0, size)
+	return
 }
 
-func (t *Turtle) snapshot() (errs *errors.ErrorList) {
-	// Acquire read-lock
-	t.mux.RLock()
-	// Defer release of read-lock
-	defer t.mux.RUnlock()
+// snapshot will archive the current state, replacing the log
+// Values are marshaled as they are archived, so only a single marshaled value is held at a time.
+// This is used when closing and repairing, Compact does not hold the write-lock while values are marshaled.
+// Note: The write-lock is expected to be held by the caller, readers are not blocked
+func (t *Turtle[V]) snapshot() (errs *errors.ErrorList) {
+	var records, size uint64
 	// Initialize errorlist before using
 	errs = &errors.ErrorList{}
+	// Current state to archive
+	st := t.st.Load()
 
-	errs.Push(t.mrT.Archive(func(txn *mrT.Txn) (err error) {
-		// Iterate through all items
-		for key, value := range t.s {
-			var b []byte
-			// Marshal the value as bytes
-			if b, err = t.mfn(value); err != nil {
-				errs.Push(err)
-				err = nil
-				// We don't necessarily need to stop the world for marshal errors,
-				// add to errors list and move on
-				continue
-			}
+	// Ensure records are sealed with the active key
+	if err := t.c.refresh(); err != nil {
+		errs.Push(err)
+		return
+	}
+
+	err := t.mrT.Archive(func(txn *mrT.Txn) (err error) {
+		records, size, err = t.archiveState(txn.Put, st, errs)
+		return
+	})
+
+	if err != nil {
+		errs.Push(err)
+		return
+	}
+
+	t.archived(records, size, 0, errs)
+	if t.vs != nil {
+		// Replace the value file as well, so that it does not grow without bound
+		errs.Push(t.compactValues())
+	}
+
+	return
+}
+
+// archived will reset the log state once the log has been replaced
+// The provided number of transactions were committed after the archived state (see compact)
+func (t *Turtle[V]) archived(records, size, txns uint64, errs *errors.ErrorList) {
+	// Log has been replaced, it now describes the records being written
+	t.formatted = true
+	// Reset compaction counters
+	atomic.StoreUint64(&t.logRecords, records)
+	atomic.StoreUint64(&t.logSize, size)
+	atomic.StoreUint64(&t.txns, txns)
+	// Sync the replaced log according to the durability
+	errs.Push(t.syncArchived())
+}
+
+// archiveState will put all the items and bucket markers within a state using the provided func
+// Values are compressed and records are sealed before they are put, when enabled
+// The number and size of the records put are returned
+func (t *Turtle[V]) archiveState(put putFn, st *state[V], errs *errors.ErrorList) (records, size uint64, err error) {
+	var n uint64
+	// Count the size of the records as they are stored
+	put = countSize(put, &size)
+	// Put the format header first, it is stored as-is so that it can be read before the records
+	if err = t.putFormat(put); err != nil {
+		return
+	}
+
+	put = t.z.wrap(t.c.wrap(t.sum.wrap(put)))
+	// Archive root items
+	if records, err = t.archiveStore(put, "", st.s, errs); err != nil {
+		return
+	}
+
+	// Count the format header
+	records++
+
+	// Iterate through all buckets
+	for name, s := range st.b {
+		// Put the bucket marker
+		if err = put([]byte(getBucketKey(name)), nil); err != nil {
+			return
+		}
+
+		// Archive bucket items
+		if n, err = t.archiveStore(put, name, s, errs); err != nil {
+			return
+		}
+
+		records += n + 1
+	}
+
+	return
+}
+
+// archiveStore will put all the items within a store using the provided func
+// Keys are archived as-is when no bucket name is provided
+func (t *Turtle[V]) archiveStore(put putFn, name string, s store[V], errs *errors.ErrorList) (records uint64, err error) {
+	// Iterate through all items
+	now := time.Now().UnixNano()
+	s.root.walk(func(n *node[V]) (end bool) {
+		if n.isExpired(now) {
+			// Expired items do not need to be archived
+			return
+		}
+
+		key := n.key
+		// Marshal the value as bytes
+		b, merr := n.marshal(t.mfn)
+		if merr != nil {
+			// We don't necessarily need to stop the world for marshal errors,
+			// add to errors list and move on
+			errs.Push(merr)
+			return
+		}
+
+		if len(name) > 0 {
+			// Store belongs to a bucket, encode the key
+			key = getBucketEntryKey(name, key)
+		}
+
+		// Put the updated bytes to the back-end
+		// Errors on put are something we need to immediately yield for.
+		// The only possible errors we would encounter are:
+		// 	1. Disk issues
+		// 	2. Middleware issues
+		// Both of which would occur for every subsequent item
+		if err = put([]byte(key), b); err != nil {
+			return true
+		}
+
+		if records++; n.expires == 0 {
+			return
+		}
+
+		// Put the expiry to the back-end, this must directly follow the put
+		err = put([]byte(getTTLKey(key)), encodeExpiry(n.expires))
+		records++
+		return err != nil
+	})
+
+	return
+}
+
+// newRTxn will return a read transaction for the current state
+func (t *Turtle[V]) newRTxn() *RTxn[V] {
+	var txn RTxn[V]
+	// Current state
+	st := t.st.Load()
+	// Assign store to txn's store field
+	txn.s = st.s
+	// Assign buckets to txn's buckets field
+	txn.b = st.b
+	// Assign secondary indexes to txn's indexes field
+	txn.idx = st.idx
+	return &txn
+}
+
+// newWTxn will return a write transaction for the current state
+// Note: The write-lock is expected to be held by the caller
+func (t *Turtle[V]) newWTxn() *WTxn[V] {
+	var txn WTxn[V]
+	// Current state
+	st := t.st.Load()
+	// Assign store to txn's store field
+	txn.s = st.s
+	// Create new txnStore
+	txn.ts = make(txnStore[V])
+	// Set parent transaction of the root bucket
+	txn.txn = &txn
+	// Assign buckets to txn's buckets field
+	txn.b = st.b
+	// Create new txnBuckets
+	txn.tb = make(txnBuckets[V])
+	// Assign expiry index to txn's expiry index field
+	txn.ttl = st.ttl
+	// Assign secondary indexes to txn's indexes field
+	txn.idx = st.idx
+	// Set marshal func
+	txn.mfn = t.mfn
+	// Set value file
+	txn.vs = t.vs
+	// Set record cipher
+	txn.c = t.c
+	// Set record compressor
+	txn.z = t.z
+	// Set record checksum
+	txn.sum = t.sum
+	return &txn
+}
+
+// commit will log a write transaction to disk and set the merged state as the current state
+// Note: The write-lock is expected to be held by the caller
+func (t *Turtle[V]) commit(txn *WTxn[V]) (err error) {
+	// Ensure indexes are not violated before anything is written
+	if err = txn.checkIndexes(); err != nil {
+		return
+	}
+
+	// Ensure new records are sealed with the active key
+	if err = t.c.refresh(); err != nil {
+		return
+	}
+
+	// Commit changes, the format header is written first when the log does not describe the records being written
+	header := !t.formatted
+	var jt *journalTxn
+	if err = t.mrT.Txn(func(mt *mrT.Txn) (err error) {
+		var lt logTxn = mt
+		if t.journal != nil {
+			// A snapshot is being written, record the lines so they can follow the snapshot
+			jt = &journalTxn{txn: mt}
+			lt = jt
+		}
 
-			// Put the updated bytes to the back-end
-			if err = txn.Put([]byte(key), b); err != nil {
-				// Errors on put are something we need to immediately yield for.
-				// The only possible errors we would encounter are:
-				// 	1. Disk issues
-				// 	2. Middleware issues
-				// Both of which would occur for every subsequent item
+		if header {
+			if err = t.putFormat(lt.Put); err != nil {
 				return
 			}
 		}
 
+		return txn.commit(lt)
+	}); err != nil {
 		return
-	}))
+	}
+
+	if header {
+		// Log now describes the records being written
+		t.formatted = true
+		txn.records++
+		txn.size += uint64(formatSize)
+	}
+
+	if jt != nil {
+		t.journal.push(jt)
+	}
+
+	// Update compaction counters
+	atomic.AddUint64(&t.logRecords, txn.records)
+	atomic.AddUint64(&t.logSize, txn.size)
+	atomic.AddUint64(&t.txns, 1)
+	t.notifyCompactor()
 
+	// Sync the log according to the durability
+	// Note: The changes are still applied when the sync fails, as they have been written to the log
+	if serr := t.syncCommitted(); serr != nil {
+		err = &NotDurableError{Err: serr}
+	}
+
+	// Merge changes and set the resulting state as the current state
+	t.st.Store(txn.merge())
+	// Increment commit sequence
+	t.seq++
+	// Notify watchers of the changes
+	t.notify(txn)
 	return
 }
 
-func (t *Turtle) Read(fn TxnFn) (err error) {
-	var txn RTxn
-	// Acquire read-lock
-	t.mux.RLock()
-	// Defer release of read-lock
-	defer t.mux.RUnlock()
-
+// Read will create a read transaction
+// Read transactions do not acquire any locks, they operate on the state
+// which was current when the transaction began and will not observe
+// updates which are committed while the transaction is in progress
+func (t *Turtle[V]) Read(fn TxnFn[V]) (err error) {
 	if t.isClosed() {
 		// DB is closed and we cannot perform any actions, return with error
 		return errors.ErrIsClosed
 	}
 
-	// Assign store to txn's store field
-	txn.s = t.s
+	txn := t.newRTxn()
 	// Defer txn clear
 	defer txn.clear()
 
 	// Call provided func
-	return fn(&txn)
+	return fn(txn)
 }
 
 // Update will create an update transaction
-func (t *Turtle) Update(fn TxnFn) (err error) {
-	var txn WTxn
+func (t *Turtle[V]) Update(fn TxnFn[V]) (err error) {
+	if t.readOnly {
+		// Read-only databases cannot perform write actions, return with error
+		return ErrReadOnly
+	}
+
 	// Acquire write-lock
 	t.mux.Lock()
 	// Defer release of write-lock
@@ -165,39 +743,92 @@ func (t *Turtle) Update(fn TxnFn) (err error) {
 		return errors.ErrIsClosed
 	}
 
-	// Assign store to txn's store field
-	txn.s = t.s
-	// Create new txnStore
-	txn.ts = make(txnStore)
-	// Set marshal func
-	txn.mfn = t.mfn
+	txn := t.newWTxn()
 	// Defer txn clear
 	defer txn.clear()
 
 	// Call provided func
-	if err = fn(&txn); err != nil {
+	if err = fn(txn); err != nil {
 		return
 	}
+
 	// Commit changes
-	if err = t.mrT.Txn(txn.commit); err != nil {
-		return
+	return t.commit(txn)
+}
+
+// Begin will begin a transaction which is finished by calling Commit or Rollback
+// Writable transactions hold the write-lock until they are finished, read
+// transactions do not acquire any locks (see Read)
+func (t *Turtle[V]) Begin(writable bool) (tx *Tx[V], err error) {
+	if writable && t.readOnly {
+		// Read-only databases cannot perform write actions, return with error
+		return nil, ErrReadOnly
 	}
-	// Merge changes
-	txn.merge()
-	return
+
+	if writable {
+		// Acquire write-lock, this is released when the transaction is finished
+		t.mux.Lock()
+	}
+
+	if t.isClosed() {
+		if writable {
+			// Release the write-lock we just acquired
+			t.mux.Unlock()
+		}
+
+		// DB is closed and we cannot perform any actions, return with error
+		return nil, errors.ErrIsClosed
+	}
+
+	return newTx(t, writable), nil
 }
 
 // Close will close Turtle
-func (t *Turtle) Close() (err error) {
+func (t *Turtle[V]) Close() (err error) {
 	if !atomic.CompareAndSwapUint32(&t.closed, 0, 1) {
 		// DB is already closed, return with error
 		return errors.ErrIsClosed
 	}
 
+	// Stop background goroutines
+	close(t.done)
+	// Stop all watchers
+	t.closeWatchers()
+
+	if t.readOnly {
+		// Back-end was closed after loading, there is nothing to persist
+		return
+	}
+
+	// Acquire archive-lock, this waits for any in-progress compaction
+	t.archiveMux.Lock()
+	// Defer release of archive-lock
+	defer t.archiveMux.Unlock()
+	// Acquire write-lock, this waits for any in-progress writes
+	t.mux.Lock()
+	// Defer release of write-lock
+	defer t.mux.Unlock()
+
 	var errs errors.ErrorList
-	// Attempt to snapshot
-	errs.Push(t.snapshot())
+	if !t.skipSnapshot {
+		// Attempt to snapshot
+		errs.Push(t.snapshot())
+	} else if t.unsynced {
+		// Log is not being replaced, sync any unsynced transactions
+		errs.Push(t.syncLog(false))
+	}
+
 	// Close file back-end
-	errs.Push(t.mrT.Close())
+	merr := t.mrT.Close()
+	errs.Push(merr)
+	if t.vs != nil {
+		if merr == nil {
+			// The log will no longer change, index the value file so it can be re-used when re-opened
+			errs.Push(t.vs.writeIndex(t.st.Load(), logFile(t.name, t.path)))
+		}
+
+		errs.Push(t.vs.close())
+	}
+
 	return errs.Err()
 }
@@ -1,14 +1,31 @@
 package turtle
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/itsmontoya/mrT"
+	"github.com/missionMeteora/toolkit/errors"
 )
 
 func TestMain(t *testing.T) {
 	var (
-		tdb *Turtle
+		tdb *Turtle[any]
 		err error
 	)
 
@@ -16,7 +33,7 @@ func TestMain(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err = tdb.Update(func(txn Txn) (err error) {
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
 		ts := &testStruct{
 			Name: "John Doe",
 			Age:  32,
@@ -27,7 +44,7 @@ func TestMain(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err = tdb.Read(func(txn Txn) (err error) {
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
 		ts := &testStruct{
 			Name: "Foo",
 			Age:  13,
@@ -37,7 +54,7 @@ func TestMain(t *testing.T) {
 			return fmt.Errorf("nil error encountered when error was expected")
 		}
 
-		var val Value
+		var val any
 		if val, err = txn.Get("0"); err != nil {
 			return
 		}
@@ -68,10 +85,10 @@ func TestMain(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err = tdb.Read(func(txn Txn) (err error) {
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
 		var (
 			ts  *testStruct
-			val Value
+			val any
 		)
 
 		if val, err = txn.Get("0"); err != nil {
@@ -97,7 +114,7 @@ func TestMain(t *testing.T) {
 	}
 }
 
-func testMarshal(val Value) (b []byte, err error) {
+func testMarshal(val any) (b []byte, err error) {
 	var (
 		ts *testStruct
 		ok bool
@@ -111,7 +128,7 @@ func testMarshal(val Value) (b []byte, err error) {
 	return json.Marshal(ts)
 }
 
-func testUnmarshal(b []byte) (val Value, err error) {
+func testUnmarshal(b []byte) (val any, err error) {
 	var ts testStruct
 	if err = json.Unmarshal(b, &ts); err != nil {
 		return
@@ -125,3 +142,3221 @@ type testStruct struct {
 	Name string `json:"name"`
 	Age  int    `json:"age"`
 }
+
+func TestBuckets(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("buckets", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		var bkt Bucket[any]
+		if bkt, err = txn.CreateBucket("users"); err != nil {
+			return
+		}
+
+		if err = bkt.Put("0", &testStruct{Name: "John Doe", Age: 32}); err != nil {
+			return
+		}
+
+		if _, err = txn.CreateBucket("users"); err != ErrBucketExists {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrBucketExists, err)
+		}
+
+		if _, err = txn.CreateBucket("sessions"); err != nil {
+			return
+		}
+
+		return txn.Put("0", &testStruct{Name: "Root", Age: 1})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.DeleteBucket("sessions")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = New("buckets", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Bucket("sessions"); err != ErrBucketDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrBucketDoesNotExist, err)
+		}
+
+		var bkt Bucket[any]
+		if bkt, err = txn.Bucket("users"); err != nil {
+			return
+		}
+
+		var val any
+		if val, err = bkt.Get("0"); err != nil {
+			return
+		}
+
+		if ts := val.(*testStruct); ts.Name != "John Doe" {
+			return fmt.Errorf("invalid name provided, expected %s and received %s", "John Doe", ts.Name)
+		}
+
+		if val, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		if ts := val.(*testStruct); ts.Name != "Root" {
+			return fmt.Errorf("invalid name provided, expected %s and received %s", "Root", ts.Name)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("cursor", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		for _, key := range []string{"b", "d", "f"} {
+			if err = txn.Put(key, &testStruct{Name: key}); err != nil {
+				return
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("a", &testStruct{Name: "a"}); err != nil {
+			return
+		}
+
+		if err = txn.Put("e", &testStruct{Name: "e"}); err != nil {
+			return
+		}
+
+		if err = txn.Delete("d"); err != nil {
+			return
+		}
+
+		return testCursor(txn.Cursor(), []string{"a", "b", "e", "f"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		w := txn.(*WTxn[any])
+		sp := w.Savepoint()
+		if err = txn.Put("z", &testStruct{Name: "z"}); err != nil {
+			return
+		}
+
+		// Pending actions are captured when the cursor is created, rolling back must not affect the cursor
+		c := txn.Cursor()
+		if err = w.RollbackTo(sp); err != nil {
+			return
+		}
+
+		if err = testCursor(c, []string{"a", "b", "e", "f", "z"}); err != nil {
+			return
+		}
+
+		// Existing keys which are put while iterating must still be visited
+		var keys []string
+		if err = txn.ForEachPrefix("", func(key string, _ any) (end bool) {
+			if key == "a" {
+				err = txn.Put("e", &testStruct{Name: "e"})
+			}
+
+			keys = append(keys, key)
+			return err != nil
+		}); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[a b e f]" {
+			return fmt.Errorf("invalid keys, expected %v and received %v", "[a b e f]", keys)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = New("cursor", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if err = testCursor(txn.Cursor(), []string{"a", "b", "e", "f"}); err != nil {
+			return
+		}
+
+		c := txn.Cursor()
+		if key, _, _ := c.Seek("c"); key != "e" {
+			return fmt.Errorf("invalid key, expected %s and received %s", "e", key)
+		}
+
+		if key, _, _ := c.Prev(); key != "b" {
+			return fmt.Errorf("invalid key, expected %s and received %s", "b", key)
+		}
+
+		if _, _, ok := c.Seek("g"); ok {
+			return fmt.Errorf("invalid seek, expected no key to be found")
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testCursor(c *Cursor[any], expected []string) (err error) {
+	var keys []string
+	for key, _, ok := c.First(); ok; key, _, ok = c.Next() {
+		keys = append(keys, key)
+	}
+
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		return fmt.Errorf("invalid keys, expected %v and received %v", expected, keys)
+	}
+
+	keys = keys[:0]
+	for key, val, ok := c.Last(); ok; key, val, ok = c.Prev() {
+		if val.(*testStruct).Name != key {
+			return fmt.Errorf("invalid value for key %s: %v", key, val)
+		}
+
+		keys = append([]string{key}, keys...)
+	}
+
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		return fmt.Errorf("invalid reverse keys, expected %v and received %v", expected, keys)
+	}
+
+	return
+}
+
+func TestForEachPrefixAndRange(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("scan", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		for _, key := range []string{"user:2", "user:1", "session:1", "user:3", "zebra"} {
+			if err = txn.Put(key, &testStruct{Name: key}); err != nil {
+				return
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Delete("user:2"); err != nil {
+			return
+		}
+
+		if err = txn.Put("user:0", &testStruct{Name: "user:0"}); err != nil {
+			return
+		}
+
+		var keys []string
+		if err = txn.ForEachPrefix("user:", func(key string, _ any) (end bool) {
+			keys = append(keys, key)
+			return
+		}); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[user:0 user:1 user:3]" {
+			return fmt.Errorf("invalid prefix keys: %v", keys)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var keys []string
+		if err = txn.ForEachRange("session:", "user:3", func(key string, _ any) (end bool) {
+			keys = append(keys, key)
+			return
+		}); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[session:1 user:0 user:1]" {
+			return fmt.Errorf("invalid range keys: %v", keys)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadIsolation(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("isolation", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		// Update within a read, this would deadlock if readers blocked writers
+		if err = tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put("0", &testStruct{Name: "Jane Doe"})
+		}); err != nil {
+			return
+		}
+
+		var val any
+		if val, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		if ts := val.(*testStruct); ts.Name != "John Doe" {
+			return fmt.Errorf("invalid name provided, expected %s and received %s", "John Doe", ts.Name)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		if ts := val.(*testStruct); ts.Name != "Jane Doe" {
+			return fmt.Errorf("invalid name provided, expected %s and received %s", "Jane Doe", ts.Name)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBegin(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		tx  *Tx[any]
+		err error
+	)
+
+	if tdb, err = New("begin", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if tx, err = tdb.Begin(true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tx.Put("0", &testStruct{Name: "John Doe"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tx.Commit(); err != ErrTxDone {
+		t.Fatalf("invalid error, expected %v and received %v", ErrTxDone, err)
+	}
+
+	// Finished transactions cannot be used
+	if err = tx.Put("1", &testStruct{Name: "Jane Doe"}); err != ErrTxDone {
+		t.Fatalf("invalid error, expected %v and received %v", ErrTxDone, err)
+	}
+
+	if _, err = tx.Get("0"); err != ErrTxDone {
+		t.Fatalf("invalid error, expected %v and received %v", ErrTxDone, err)
+	}
+
+	if _, err = tx.CreateBucket("bucket"); err != ErrTxDone {
+		t.Fatalf("invalid error, expected %v and received %v", ErrTxDone, err)
+	}
+
+	// Transaction handles can be used as a Txn, the finished state is still checked
+	var txn Txn[any] = tx
+	if err = txn.Delete("0"); err != ErrTxDone {
+		t.Fatalf("invalid error, expected %v and received %v", ErrTxDone, err)
+	}
+
+	if c := tx.Cursor(); c.Err() != ErrTxDone {
+		t.Fatalf("invalid error, expected %v and received %v", ErrTxDone, c.Err())
+	} else if _, _, ok := c.First(); ok {
+		t.Fatal("expected empty cursor for finished transaction")
+	}
+
+	if tx, err = tdb.Begin(true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tx.Put("1", &testStruct{Name: "Jane Doe"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tx, err = tdb.Begin(false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = tx.Get("0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = tx.Get("1"); err != ErrKeyDoesNotExist {
+		t.Fatalf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+	}
+
+	if err = tx.Put("1", &testStruct{Name: "Jane Doe"}); err != ErrNotWriteTxn {
+		t.Fatalf("invalid error, expected %v and received %v", ErrNotWriteTxn, err)
+	}
+
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxLeak(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	logs := make(chanLogger, 1)
+	if tdb, err = New("leak", "./data", testMarshal, testUnmarshal, WithLogger(logs)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	func() {
+		tx, err := tdb.Begin(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = tx.Put("0", &testStruct{Name: "John Doe"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Transaction is abandoned without calling Commit or Rollback
+	}()
+
+	var msg string
+	for i := 0; len(msg) == 0; i++ {
+		if i == 100 {
+			t.Fatal("leaked transaction was not rolled back")
+		}
+
+		runtime.GC()
+		select {
+		case msg = <-logs:
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	if !strings.Contains(msg, "garbage collected without calling Commit or Rollback") {
+		t.Fatalf("invalid log output: %s", msg)
+	}
+
+	// The write-lock was released and the put was discarded
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("0"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// chanLogger is a Logger which sends each message to a channel
+type chanLogger chan string
+
+// Printf will send the formatted message to the channel
+func (c chanLogger) Printf(format string, v ...any) {
+	c <- fmt.Sprintf(format, v...)
+}
+
+func TestSavepoint(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("savepoint", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		w := txn.(*WTxn[any])
+		if err = w.Put("0", &testStruct{Name: "John Doe"}); err != nil {
+			return
+		}
+
+		sp := w.Savepoint()
+		if err = w.Put("0", &testStruct{Name: "Invalid"}); err != nil {
+			return
+		}
+
+		if err = w.Put("1", &testStruct{Name: "Invalid"}); err != nil {
+			return
+		}
+
+		if _, err = w.CreateBucket("invalid"); err != nil {
+			return
+		}
+
+		if err = w.RollbackTo(sp); err != nil {
+			return
+		}
+
+		if err = w.Nested(func(txn Txn[any]) (err error) {
+			if err = txn.Put("2", &testStruct{Name: "Invalid"}); err != nil {
+				return
+			}
+
+			return fmt.Errorf("validation failed")
+		}); err == nil {
+			return fmt.Errorf("nil error encountered when error was expected")
+		}
+
+		return w.Nested(func(txn Txn[any]) (err error) {
+			return txn.Put("3", &testStruct{Name: "Jane Doe"})
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		w := txn.(*WTxn[any])
+		sp1 := w.Savepoint()
+		if err = txn.Put("4", &testStruct{Name: "Jane Doe"}); err != nil {
+			return
+		}
+
+		sp2 := w.Savepoint()
+		if err = txn.Put("5", &testStruct{Name: "Jane Doe"}); err != nil {
+			return
+		}
+
+		if err = w.RollbackTo(sp1); err != nil {
+			return
+		}
+
+		// A savepoint created after rolling back must not revive the invalidated savepoint
+		sp3 := w.Savepoint()
+		if err = w.RollbackTo(sp2); err != ErrInvalidSavepoint {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrInvalidSavepoint, err)
+		}
+
+		if _, err = txn.Get("5"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		if err = w.RollbackTo(sp3); err != nil {
+			return
+		}
+
+		return w.RollbackTo(sp1)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		if ts := val.(*testStruct); ts.Name != "John Doe" {
+			return fmt.Errorf("invalid name provided, expected %s and received %s", "John Doe", ts.Name)
+		}
+
+		for _, key := range []string{"1", "2", "4", "5"} {
+			if _, err = txn.Get(key); err != ErrKeyDoesNotExist {
+				return fmt.Errorf("invalid error for key %s, expected %v and received %v", key, ErrKeyDoesNotExist, err)
+			}
+		}
+
+		if _, err = txn.Bucket("invalid"); err != ErrBucketDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrBucketDoesNotExist, err)
+		}
+
+		_, err = txn.Get("3")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.CreateIndex("age", testIndexAge); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		w := txn.(*WTxn[any])
+		sp := w.Savepoint()
+		if err = txn.Put("6", &testStruct{Name: "6", Age: 40}); err != nil {
+			return
+		}
+
+		c := txn.Cursor()
+		idx := txn.Index("age")
+		// Rolling back while iterating must not affect the iteration
+		var keys []string
+		if err = txn.ForEachPrefix("", func(key string, _ any) (end bool) {
+			if len(keys) == 0 {
+				err = w.RollbackTo(sp)
+			}
+
+			keys = append(keys, key)
+			return err != nil
+		}); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[0 3 6]" {
+			return fmt.Errorf("invalid prefix keys, expected %v and received %v", "[0 3 6]", keys)
+		}
+
+		// Cursors and indexes retrieved before rolling back keep the actions they captured
+		keys = keys[:0]
+		for key, _, ok := c.First(); ok; key, _, ok = c.Next() {
+			keys = append(keys, key)
+		}
+
+		if fmt.Sprint(keys) != "[0 3 6]" {
+			return fmt.Errorf("invalid cursor keys, expected %v and received %v", "[0 3 6]", keys)
+		}
+
+		if keys, err = idx.Get("40"); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[6]" {
+			return fmt.Errorf("invalid index keys, expected %v and received %v", "[6]", keys)
+		}
+
+		// Cursors and indexes retrieved after rolling back do not hold the discarded actions
+		if keys, err = txn.Index("age").Get("40"); err != nil {
+			return
+		}
+
+		if len(keys) != 0 {
+			return fmt.Errorf("invalid index keys, expected none and received %v", keys)
+		}
+
+		keys = keys[:0]
+		c = txn.Cursor()
+		for key, _, ok := c.Last(); ok; key, _, ok = c.Prev() {
+			keys = append(keys, key)
+		}
+
+		if fmt.Sprint(keys) != "[3 0]" {
+			return fmt.Errorf("invalid cursor keys, expected %v and received %v", "[3 0]", keys)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		wg  sync.WaitGroup
+		err error
+	)
+
+	if tdb, err = New("batch", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	errs := make([]error, 10)
+	// Every call is gathered into a single batch, which is committed once full
+	if err = tdb.SetMaxBatchSize(len(errs)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.SetMaxBatchDelay(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	txns := tdb.Stats().Transactions
+	attempts := make([]int32, len(errs))
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = tdb.Batch(func(txn Txn[any]) (err error) {
+				attempt := atomic.AddInt32(&attempts[i], 1)
+				key := strconv.Itoa(i)
+				if err = txn.Put(key, &testStruct{Name: key, Age: i}); err != nil {
+					return
+				}
+
+				switch {
+				case i == 3:
+					return fmt.Errorf("invalid key: %s", key)
+				case i == 5 && attempt == 1:
+					// Fails within the batch only, the solo retry is committed
+					return fmt.Errorf("conflict for key: %s", key)
+				}
+
+				return
+			})
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Batch is committed once, the solo retry of the fifth call is committed on it's own
+	// The solo retry of the third call fails, so it is not committed
+	if n := tdb.Stats().Transactions - txns; n != 2 {
+		t.Fatalf("invalid number of transactions, expected %d and received %d", 2, n)
+	}
+
+	for i, n := range attempts {
+		expected := int32(1)
+		if i == 3 || i == 5 {
+			// Failed calls are retried solo
+			expected = 2
+		}
+
+		if n != expected {
+			t.Fatalf("invalid number of attempts for call %d, expected %d and received %d", i, expected, n)
+		}
+	}
+
+	for i, err := range errs {
+		if i == 3 {
+			if err == nil {
+				t.Fatal("nil error encountered when error was expected")
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		for i := range errs {
+			_, err = txn.Get(strconv.Itoa(i))
+			switch {
+			case i == 3 && err != ErrKeyDoesNotExist:
+				return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+			case i != 3 && err != nil:
+				return
+			}
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.SetMaxBatchSize(0); err != ErrInvalidMaxBatchSize {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidMaxBatchSize, err)
+	}
+
+	// Batches of two are committed once full, long before the delay
+	if err = tdb.SetMaxBatchSize(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.SetMaxBatchDelay(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// A panicking call is returned to it's caller, the other call within the batch is committed
+	perrs := make([]error, 2)
+	pattempts := make([]int32, 2)
+	txns = tdb.Stats().Transactions
+	for i := range perrs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			perrs[i] = tdb.Batch(func(txn Txn[any]) (err error) {
+				atomic.AddInt32(&pattempts[i], 1)
+				if err = txn.Put("panic-"+strconv.Itoa(i), &testStruct{Name: "John Doe"}); err != nil {
+					return
+				}
+
+				if i == 1 {
+					panic("oh no")
+				}
+
+				return
+			})
+		}(i)
+	}
+
+	wg.Wait()
+	if perrs[0] != nil {
+		t.Fatal(perrs[0])
+	}
+
+	if perr, ok := perrs[1].(*PanicError); !ok || perr.Value != "oh no" {
+		t.Fatalf("invalid error, expected *PanicError and received %v", perrs[1])
+	}
+
+	// Panicking calls are not retried solo, only the batch is committed
+	if pattempts[1] != 1 {
+		t.Fatalf("invalid number of attempts, expected %d and received %d", 1, pattempts[1])
+	}
+
+	if n := tdb.Stats().Transactions - txns; n != 1 {
+		t.Fatalf("invalid number of transactions, expected %d and received %d", 1, n)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("panic-1"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		_, err = txn.Get("panic-0")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("ttl", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.PutWithTTL("short", &testStruct{Name: "short"}, 50*time.Millisecond); err != nil {
+			return
+		}
+
+		if err = txn.PutWithTTL("long", &testStruct{Name: "long"}, time.Hour); err != nil {
+			return
+		}
+
+		return txn.PutWithTTL("invalid", &testStruct{Name: "invalid"}, 0)
+	}); err != ErrInvalidTTL {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidTTL, err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.PutWithTTL("short", &testStruct{Name: "short"}, 50*time.Millisecond); err != nil {
+			return
+		}
+
+		return txn.PutWithTTL("long", &testStruct{Name: "long"}, time.Hour)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = New("ttl", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys survive re-opening until they expire
+	// Note: Only the long key is checked, the short key may already have expired on slow runs
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		_, err = txn.Get("long")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("short"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		var keys []string
+		if err = txn.ForEach(func(key string, _ any) (end bool) {
+			keys = append(keys, key)
+			return
+		}); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[long]" {
+			return fmt.Errorf("invalid keys: %v", keys)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.reap(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if txn.(*RTxn[any]).s.exists("short") {
+			return fmt.Errorf("expired key was not removed")
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("watch", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	events, cancel := tdb.Watch("user:")
+	defer cancel()
+
+	bevents, bcancel := tdb.WatchBucket("users", "user:")
+	defer bcancel()
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("user:0", &testStruct{Name: "John Doe"}); err != nil {
+			return
+		}
+
+		var b Bucket[any]
+		if b, err = txn.CreateBucket("users"); err != nil {
+			return
+		}
+
+		// Bucket keys are only sent to watchers of the bucket
+		if err = b.Put("user:1", &testStruct{Name: "Jane Doe"}); err != nil {
+			return
+		}
+
+		return txn.Put("session:0", &testStruct{Name: "Ignored"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Delete("user:0")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ev := <-events
+	if ev.Key != "user:0" || ev.Deleted || ev.Value.(*testStruct).Name != "John Doe" || ev.Seq != 1 {
+		t.Fatalf("invalid event: %+v", ev)
+	}
+
+	if ev = <-events; ev.Key != "user:0" || !ev.Deleted || ev.Seq != 2 {
+		t.Fatalf("invalid event: %+v", ev)
+	}
+
+	if ev = <-bevents; ev.Bucket != "users" || ev.Key != "user:1" || ev.Deleted || ev.Seq != 1 {
+		t.Fatalf("invalid bucket event: %+v", ev)
+	}
+
+	select {
+	case ev = <-bevents:
+		t.Fatalf("invalid bucket event, expected none and received %+v", ev)
+	default:
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := <-events; ok {
+		t.Fatal("events channel was not closed")
+	}
+}
+
+func TestWatchOverflow(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("overflow", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	events, cancel := tdb.Watch("")
+	defer cancel()
+
+	// Commit more events than a watcher will queue while nothing is consuming them
+	n := MaxWatchQueue + 100
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		for i := 0; i < n; i++ {
+			if err = txn.Put(fmt.Sprintf("%05d", i), &testStruct{Name: "John Doe"}); err != nil {
+				return
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ev := <-events; !ev.Overflow || ev.Seq != 1 || len(ev.Key) > 0 {
+		t.Fatalf("invalid event, expected overflow and received %+v", ev)
+	}
+
+	// Events after the overflow are delivered
+	for i := MaxWatchQueue + 1; i < n; i++ {
+		if ev := <-events; ev.Key != fmt.Sprintf("%05d", i) {
+			t.Fatalf("invalid event key, expected %05d and received %+v", i, ev)
+		}
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("index", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("0", &testStruct{Name: "John Doe", Age: 32}); err != nil {
+			return
+		}
+
+		return txn.Put("1", &testStruct{Name: "Jane Doe", Age: 32})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.CreateIndex("age", testIndexAge); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.CreateIndex("age", testIndexAge); err != ErrIndexExists {
+		t.Fatalf("invalid error, expected %v and received %v", ErrIndexExists, err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("1", &testStruct{Name: "Jane Doe", Age: 33}); err != nil {
+			return
+		}
+
+		if err = txn.Put("2", &testStruct{Name: "Foo", Age: 32}); err != nil {
+			return
+		}
+
+		var keys []string
+		if keys, err = txn.Index("age").Get("32"); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[0 2]" {
+			return fmt.Errorf("invalid pending index keys: %v", keys)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var keys []string
+		if keys, err = txn.Index("age").Get("32"); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[0 2]" {
+			return fmt.Errorf("invalid index keys: %v", keys)
+		}
+
+		if keys, err = txn.Index("age").Get("33"); err != nil {
+			return
+		}
+
+		if fmt.Sprint(keys) != "[1]" {
+			return fmt.Errorf("invalid index keys: %v", keys)
+		}
+
+		if _, err = txn.Index("name").Get("Foo"); err != ErrIndexDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrIndexDoesNotExist, err)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.CreateIndex("name", testIndexName); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("3", &testStruct{Name: "Foo\x00Bar"})
+	}); err != ErrInvalidIndexKey {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidIndexKey, err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("3"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testIndexAge(key string, val any) []string {
+	return []string{strconv.Itoa(val.(*testStruct).Age)}
+}
+
+func TestUniqueIndex(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("unique", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll("./data")
+
+	if err = tdb.CreateUniqueIndex("name", testIndexName); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("1", &testStruct{Name: "Jane Doe"}); err != nil {
+			return
+		}
+
+		return txn.Put("2", &testStruct{Name: "John Doe"})
+	})
+
+	uerr, ok := err.(*UniqueError)
+	if !ok {
+		t.Fatalf("invalid error, expected *UniqueError and received %v", err)
+	}
+
+	if uerr.Index != "name" || uerr.Key != "2" || uerr.ExistingKey != "0" {
+		t.Fatalf("invalid unique error: %+v", uerr)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		// Moving the name to a new key is valid when the original key gives it up
+		if err = txn.Put("0", &testStruct{Name: "Foo"}); err != nil {
+			return
+		}
+
+		return txn.Put("2", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		w := txn.(*WTxn[any])
+		sp := w.Savepoint()
+		if err = w.Put("3", &testStruct{Name: "Bar"}); err != nil {
+			return
+		}
+
+		// Rolling back releases the index keys claimed since the savepoint
+		if err = w.RollbackTo(sp); err != nil {
+			return
+		}
+
+		return w.Put("4", &testStruct{Name: "Bar"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = New("unique", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("1"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = New("unique-order", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.CreateUniqueIndex("name", testIndexName); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.CreateUniqueIndex("age", testIndexAge); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe", Age: 32})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both indexes are violated, indexes are checked in order so the same error is always returned
+	for i := 0; i < 20; i++ {
+		err = tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put("1", &testStruct{Name: "John Doe", Age: 32})
+		})
+
+		if uerr, ok := err.(*UniqueError); !ok || uerr.Index != "age" {
+			t.Fatalf("invalid error, expected *UniqueError for %s and received %v", "age", err)
+		}
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testIndexName(key string, val any) []string {
+	return []string{val.(*testStruct).Name}
+}
+
+func TestCompaction(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("compaction", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	if err = tdb.SetCompactionPolicy(CompactionPolicy{SizeRatio: -1}); err != ErrInvalidCompactionPolicy {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidCompactionPolicy, err)
+	}
+
+	if err = tdb.SetCompactionPolicy(CompactionPolicy{Transactions: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if err = tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put(strconv.Itoa(i%5), &testStruct{Name: strconv.Itoa(i)})
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wait for the compactor to catch up
+	for i := 0; atomic.LoadUint64(&tdb.logRecords) >= 100; i++ {
+		if i == 100 {
+			t.Fatalf("log was not compacted, %d records remain", atomic.LoadUint64(&tdb.logRecords))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = tdb.SetCompactionPolicy(CompactionPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Format header and a record for each key
+	if n := atomic.LoadUint64(&tdb.logRecords); n != 6 {
+		t.Fatalf("invalid number of records, expected %d and received %d", 6, n)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Compact(); err != errors.ErrIsClosed {
+		t.Fatalf("invalid error, expected %v and received %v", errors.ErrIsClosed, err)
+	}
+
+	if tdb, err = New("compaction", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("4"); err != nil {
+			return
+		}
+
+		if name := val.(*testStruct).Name; name != "99" {
+			return fmt.Errorf("invalid value, expected %s and received %s", "99", name)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Log is compacted once it is more than twice the size of the live data
+	if err = tdb.SetCompactionPolicy(CompactionPolicy{SizeRatio: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	large := strings.Repeat("John Doe ", 10<<10)
+	for i := 0; i < 50; i++ {
+		if err = tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put(strconv.Itoa(i%5), &testStruct{Name: large})
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wait for the compactor to catch up, over 4MB has been written
+	for i := 0; ; i++ {
+		s := tdb.Stats()
+		if s.LogSize < MinCompactionSize || s.LogSize <= 2*s.LiveSize {
+			break
+		}
+
+		if i == 100 {
+			t.Fatalf("log was not compacted, log size is %d and live size is %d", s.LogSize, s.LiveSize)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writers are not blocked while values are marshaled
+	var blocking atomic.Bool
+	block := make(chan struct{})
+	marshaling := make(chan struct{}, 1)
+	mfn := func(val any) ([]byte, error) {
+		if ts, ok := val.(*testStruct); ok && ts.Name == "block" && blocking.Load() {
+			marshaling <- struct{}{}
+			<-block
+		}
+
+		return testMarshal(val)
+	}
+
+	if tdb, err = New("compaction-writers", "./data", mfn, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "block"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	blocking.Store(true)
+	compacted := make(chan error, 1)
+	go func() {
+		compacted <- tdb.Compact()
+	}()
+
+	// Wait for the compaction to begin marshaling
+	<-marshaling
+	updated := make(chan error, 1)
+	go func() {
+		updated <- tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put("1", &testStruct{Name: "John Doe"})
+		})
+	}()
+
+	select {
+	case err = <-updated:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("update was blocked by compaction")
+	}
+
+	blocking.Store(false)
+	close(block)
+	if err = <-compacted; err != nil {
+		t.Fatal(err)
+	}
+
+	// Format header and the first key, followed by the update committed during compaction
+	if s := tdb.Stats(); s.LogRecords != 3 || s.Transactions != 1 {
+		t.Fatalf("invalid stats, expected %d records and %d transaction and received %+v", 3, 1, s)
+	}
+
+	if _, err = os.Stat(filepath.Join("data", "compaction-writers.compact")); !os.IsNotExist(err) {
+		t.Fatalf("invalid error, expected spill file to be removed and received %v", err)
+	}
+
+	// Log must hold every committed key without relying on the snapshot on close
+	tdb.skipSnapshot = true
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = New("compaction-writers", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		for _, key := range []string{"0", "1"} {
+			if _, err = txn.Get(key); err != nil {
+				return fmt.Errorf("error getting %s: %v", key, err)
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		buf bytes.Buffer
+		err error
+	)
+
+	if tdb, err = New("backup", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+	defer os.RemoveAll("./restore")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("0", &testStruct{Name: "John Doe"}); err != nil {
+			return
+		}
+
+		if err = txn.PutWithTTL("1", &testStruct{Name: "Jane Doe"}, time.Hour); err != nil {
+			return
+		}
+
+		var bkt Bucket[any]
+		if bkt, err = txn.CreateBucket("foo"); err != nil {
+			return
+		}
+
+		return bkt.Put("2", &testStruct{Name: "Foo Bar"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Backup(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes made after the backup should not be included
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("3", &testStruct{Name: "Baz"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Restore(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), "backup", "./restore"); err != ErrInvalidBackup {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidBackup, err)
+	}
+
+	// record will return a backup header followed by the start of a record with the provided key length
+	record := func(n uint64) []byte {
+		return binary.AppendUvarint(append([]byte(backupMagic), backupVersion, backupRecord), n)
+	}
+
+	invalid := [][]byte{
+		// Garbage
+		[]byte("not a backup"),
+		// Truncated within a record
+		buf.Bytes()[:len(backupMagic)+3],
+		// Length which exceeds the maximum
+		record(1 << 62),
+		// Length which exceeds the remaining bytes
+		append(record(1<<20), "foo"...),
+	}
+
+	for i, b := range invalid {
+		if err = Restore(bytes.NewReader(b), "backup", "./restore"); err != ErrInvalidBackup {
+			t.Fatalf("invalid error for backup #%d, expected %v and received %v", i, ErrInvalidBackup, err)
+		}
+	}
+
+	if err = Restore(bytes.NewReader(buf.Bytes()), "backup", "./restore"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Restore(bytes.NewReader(buf.Bytes()), "backup", "./restore"); err != ErrDatabaseExists {
+		t.Fatalf("invalid error, expected %v and received %v", ErrDatabaseExists, err)
+	}
+
+	if tdb, err = New("backup", "./restore", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		if _, err = txn.Get("1"); err != nil {
+			return
+		}
+
+		if _, err = txn.Get("3"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		var bkt Bucket[any]
+		if bkt, err = txn.Bucket("foo"); err != nil {
+			return
+		}
+
+		_, err = bkt.Get("2")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		buf bytes.Buffer
+		err error
+	)
+
+	if tdb, err = New("export", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		for i := 0; i < 5; i++ {
+			if err = txn.Put(strconv.Itoa(i), &testStruct{Name: strconv.Itoa(i)}); err != nil {
+				return
+			}
+		}
+
+		if err = txn.PutWithTTL("5", &testStruct{Name: "5"}, time.Hour); err != nil {
+			return
+		}
+
+		// Empty buckets should be exported
+		if _, err = txn.CreateBucket("bar"); err != nil {
+			return
+		}
+
+		var bkt Bucket[any]
+		if bkt, err = txn.CreateBucket("foo"); err != nil {
+			return
+		}
+
+		return bkt.Put("6", &testStruct{Name: "6"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Export(&buf, Format(99)); err != ErrInvalidFormat {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidFormat, err)
+	}
+
+	if err = tdb.Export(&buf, FormatNDJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := bytes.Count(buf.Bytes(), []byte("\n")); n != 9 {
+		t.Fatalf("invalid number of lines, expected %d and received %d", 9, n)
+	}
+
+	// Keys which have expired since the export should not be counted as imported
+	buf.WriteString(`{"key":"7","value":{"name":"7"},"expires":1}` + "\n")
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = New("import", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []int
+	if err = tdb.ImportChunked(&buf, FormatNDJSON, 3, func(imported int) {
+		progress = append(progress, imported)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(progress) != "[3 6 7]" {
+		t.Fatalf("invalid progress, expected %v and received %v", "[3 6 7]", progress)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("4"); err != nil {
+			return
+		}
+
+		if name := val.(*testStruct).Name; name != "4" {
+			return fmt.Errorf("invalid value, expected %s and received %s", "4", name)
+		}
+
+		if tdb.st.Load().s.root.get("5").expires == 0 {
+			return fmt.Errorf("expected an expiry to be imported")
+		}
+
+		var bkt Bucket[any]
+		if bkt, err = txn.Bucket("foo"); err != nil {
+			return
+		}
+
+		if _, err = bkt.Get("6"); err != nil {
+			return
+		}
+
+		if _, err = txn.Get("7"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		_, err = txn.Bucket("bar")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only bucket records may omit the value
+	if err = tdb.Import(strings.NewReader(`{"key":"8"}`), FormatNDJSON); err != ErrInvalidFormat {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidFormat, err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values which are not valid JSON should be base64 encoded
+	var bdb *Turtle[[]byte]
+	identity := func(b []byte) ([]byte, error) { return b, nil }
+	if bdb, err = New("bytes", "./data", identity, identity); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values must be imported byte-for-byte, whether or not they are embedded
+	values := map[string]string{
+		"greeting": "hello world!",
+		"html":     `"<b>"`,
+		"padded":   " 42",
+		"spaced":   `{"a": 1}`,
+		"compact":  `{"a":1}`,
+	}
+
+	if err = bdb.Update(func(txn Txn[[]byte]) (err error) {
+		for key, value := range values {
+			if err = txn.Put(key, []byte(value)); err != nil {
+				return
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	if err = bdb.Export(&buf, FormatNDJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := bytes.Count(buf.Bytes(), []byte(`"encoding":"base64"`)); n != 3 {
+		t.Fatalf("invalid number of base64 encoded values, expected %d and received %d: %s", 3, n, buf.String())
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte(`"value":"<b>"`)) {
+		t.Fatalf("expected value to be embedded without escaping: %s", buf.String())
+	}
+
+	if err = bdb.Update(func(txn Txn[[]byte]) (err error) {
+		for key := range values {
+			if err = txn.Delete(key); err != nil {
+				return
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = bdb.Import(&buf, FormatNDJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = bdb.Read(func(txn Txn[[]byte]) (err error) {
+		for key, value := range values {
+			var b []byte
+			if b, err = txn.Get(key); err != nil {
+				return
+			}
+
+			if string(b) != value {
+				return fmt.Errorf("invalid value, expected %q and received %q", value, string(b))
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Importing an empty reader should not commit a transaction
+	seq := bdb.seq
+	if err = bdb.Import(bytes.NewReader(nil), FormatNDJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if bdb.seq != seq {
+		t.Fatalf("invalid sequence, expected %d and received %d", seq, bdb.seq)
+	}
+
+	if err = bdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if _, err = OpenReadOnly("readonly", "./data", testUnmarshal); !os.IsNotExist(err) {
+		t.Fatalf("invalid error, expected a not exist error and received %v", err)
+	}
+
+	if err = os.MkdirAll("./data", 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	// Opening a database which does not exist must not create it's log
+	if _, err = OpenReadOnly("readonly", "./data", testUnmarshal); !os.IsNotExist(err) {
+		t.Fatalf("invalid error, expected a not exist error and received %v", err)
+	}
+
+	if _, err = os.Stat("./data/readonly.tdb"); !os.IsNotExist(err) {
+		t.Fatalf("invalid error, expected a not exist error and received %v", err)
+	}
+
+	if tdb, err = New("readonly", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir("./data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := files[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read-only files and directories can be read
+	if err = os.Chmod("./data/"+files[0].Name(), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chmod("./data", 0555); err != nil {
+		t.Fatal(err)
+	}
+	// Restore permissions so the directory can be removed
+	defer os.Chmod("./data", 0755)
+
+	// Several read-only instances may be open at once
+	var a, b *Turtle[any]
+	if a, err = OpenReadOnly("readonly", "./data", testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err = OpenReadOnly("readonly", "./data", testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.Read(func(txn Txn[any]) (err error) {
+		_, err = txn.Get("0")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("1", &testStruct{Name: "Jane Doe"})
+	}); err != ErrReadOnly {
+		t.Fatalf("invalid error, expected %v and received %v", ErrReadOnly, err)
+	}
+
+	if _, err = a.Begin(true); err != ErrReadOnly {
+		t.Fatalf("invalid error, expected %v and received %v", ErrReadOnly, err)
+	}
+
+	if err = a.Compact(); err != ErrReadOnly {
+		t.Fatalf("invalid error, expected %v and received %v", ErrReadOnly, err)
+	}
+
+	if err = a.Export(io.Discard, FormatNDJSON); err != ErrNoMarshalFn {
+		t.Fatalf("invalid error, expected %v and received %v", ErrNoMarshalFn, err)
+	}
+
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.Stat("./data/" + files[0].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !after.ModTime().Equal(info.ModTime()) || after.Size() != info.Size() {
+		t.Fatal("expected files to be untouched by read-only instances")
+	}
+}
+
+func TestBounded(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if _, err = NewBounded("bounded", "./data", testMarshal, testUnmarshal, -1); err != ErrInvalidCacheSize {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidCacheSize, err)
+	}
+
+	if tdb, err = NewBounded("bounded", "./data", testMarshal, testUnmarshal, 256); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	if err = tdb.CreateIndex("name", testIndexName); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		for i := 0; i < 100; i++ {
+			if err = txn.Put(fmt.Sprintf("%03d", i), &testStruct{Name: strconv.Itoa(i % 10)}); err != nil {
+				return
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = NewBounded("bounded", "./data", testMarshal, testUnmarshal, 256); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.CreateIndex("name", testIndexName); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("000", &testStruct{Name: "foo"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("042"); err != nil {
+			return
+		}
+
+		if name := val.(*testStruct).Name; name != "2" {
+			return fmt.Errorf("invalid value, expected %s and received %s", "2", name)
+		}
+
+		var n int
+		if err = txn.ForEach(func(key string, val any) (end bool) {
+			if val.(*testStruct).Name != "foo" {
+				n++
+			}
+
+			return
+		}); err != nil {
+			return
+		}
+
+		if n != 99 {
+			return fmt.Errorf("invalid number of values, expected %d and received %d", 99, n)
+		}
+
+		var keys []string
+		if keys, err = txn.Index("name").Get("0"); err != nil {
+			return
+		}
+
+		if len(keys) != 9 {
+			return fmt.Errorf("invalid number of index keys, expected %d and received %d", 9, len(keys))
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := tdb.st.Load().s.root.get("042"); n.value != nil || n.ref == nil {
+		t.Fatal("expected value to be disk-resident")
+	}
+
+	if tdb.vs.c.size > 256 {
+		t.Fatalf("cache exceeded budget, expected at most %d and received %d", 256, tdb.vs.c.size)
+	}
+
+	// Overwrite the same keys, growing the value file with stale values
+	for i := 0; i < 10; i++ {
+		if err = tdb.Update(func(txn Txn[any]) (err error) {
+			for j := 0; j < 10; j++ {
+				if err = txn.Put(fmt.Sprintf("%03d", j), &testStruct{Name: strconv.Itoa(i)}); err != nil {
+					return
+				}
+			}
+
+			return
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Hold a view of the current state, it must remain readable once the value file is replaced
+	old := tdb.newRTxn()
+	size := tdb.vs.off
+	if err = tdb.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb.vs.off >= size {
+		t.Fatalf("expected value file to shrink, %d bytes before compaction and %d bytes after", size, tdb.vs.off)
+	}
+
+	if files, _ := filepath.Glob("./data/bounded.values*"); len(files) != 1 {
+		t.Fatalf("expected a single value file, found %v", files)
+	}
+
+	for _, txn := range []Txn[any]{old, tdb.newRTxn()} {
+		var val any
+		if val, err = txn.Get("005"); err != nil {
+			t.Fatal(err)
+		}
+
+		if name := val.(*testStruct).Name; name != "9" {
+			t.Fatalf("invalid value, expected %s and received %s", "9", name)
+		}
+
+		if val, err = txn.Get("042"); err != nil {
+			t.Fatal(err)
+		}
+
+		if name := val.(*testStruct).Name; name != "2" {
+			t.Fatalf("invalid value, expected %s and received %s", "2", name)
+		}
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Value file is indexed on close, the index offset is held within the footer
+	b, err := os.ReadFile("./data/bounded.values")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	off := int64(binary.LittleEndian.Uint64(b[len(b)-indexFooterSize:]))
+	// Replacement value files left behind by a crash are removed when opened
+	if err = os.WriteFile("./data/bounded.values.tmp", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = NewBounded("bounded", "./data", testMarshal, testUnmarshal, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := filepath.Glob("./data/bounded.values*"); len(files) != 1 {
+		t.Fatalf("expected a single value file, found %v", files)
+	}
+
+	// Value file is re-used, no values are written while loading
+	if tdb.vs.off != off {
+		t.Fatalf("invalid value file offset, expected %d and received %d", off, tdb.vs.off)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("005"); err != nil {
+			return
+		}
+
+		if name := val.(*testStruct).Name; name != "9" {
+			return fmt.Errorf("invalid value, expected %s and received %s", "9", name)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Update the log without the value file, it no longer matches and must be rebuilt
+	var udb *Turtle[any]
+	if udb, err = New("bounded", "./data", testMarshal, testUnmarshal, WithSnapshotOnClose(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = udb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("005", &testStruct{Name: "bar"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = udb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = NewBounded("bounded", "./data", testMarshal, testUnmarshal, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("005"); err != nil {
+			return
+		}
+
+		if name := val.(*testStruct).Name; name != "bar" {
+			return fmt.Errorf("invalid value, expected %s and received %s", "bar", name)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	key := StaticKey(bytes.Repeat([]byte{1}, 32))
+	if _, err = NewEncrypted("encrypted", "./data", testMarshal, testUnmarshal, StaticKey("short")); err != ErrInvalidEncryptionKey {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidEncryptionKey, err)
+	}
+
+	if tdb, err = NewEncrypted("encrypted", "./data", testMarshal, testUnmarshal, key); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("secret-key", &testStruct{Name: "John Doe"}); err != nil {
+			return
+		}
+
+		if err = txn.PutWithTTL("1", &testStruct{Name: "Jane Doe"}, time.Hour); err != nil {
+			return
+		}
+
+		var bkt Bucket[any]
+		if bkt, err = txn.CreateBucket("foo"); err != nil {
+			return
+		}
+
+		return bkt.Put("2", &testStruct{Name: "Foo Bar"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Delete("1")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys and values should not be visible within the log
+	files, _ := filepath.Glob("./data/encrypted*")
+	for _, file := range files {
+		b, _ := os.ReadFile(file)
+		if bytes.Contains(b, []byte("secret-key")) || bytes.Contains(b, []byte("John Doe")) {
+			t.Fatalf("expected records to be encrypted: %s", b)
+		}
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewEncrypted("encrypted", "./data", testMarshal, testUnmarshal, StaticKey(bytes.Repeat([]byte{2}, 32))); err != ErrDecrypt {
+		t.Fatalf("invalid error, expected %v and received %v", ErrDecrypt, err)
+	}
+
+	if tdb, err = NewEncrypted("encrypted", "./data", testMarshal, testUnmarshal, key); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("secret-key"); err != nil {
+			return
+		}
+
+		if name := val.(*testStruct).Name; name != "John Doe" {
+			return fmt.Errorf("invalid value, expected %s and received %s", "John Doe", name)
+		}
+
+		if _, err = txn.Get("1"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		var bkt Bucket[any]
+		if bkt, err = txn.Bucket("foo"); err != nil {
+			return
+		}
+
+		_, err = bkt.Get("2")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tampering with a record should be detected
+	r, err := newRecordCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ekey, evalue, err := r.seal([]byte("0"), []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evalue[len(evalue)-1] ^= 1
+	if _, _, err = r.open(ekey, evalue); err != ErrDecrypt {
+		t.Fatalf("invalid error, expected %v and received %v", ErrDecrypt, err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	k1, k2 := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	ring := NewKeyRing("1", k1)
+	if tdb, err = NewEncrypted("rekey", "./data", testMarshal, testUnmarshal, ring); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate keys, new writes should use the new active key
+	ring.Add("2", k2)
+	if err = ring.SetActive("2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("1", &testStruct{Name: "Jane Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both keys are needed until the log has been rewritten
+	if _, err = NewEncrypted("rekey", "./data", testMarshal, testUnmarshal, NewKeyRing("2", k2)); err != ErrKeyNotFound {
+		t.Fatalf("invalid error, expected %v and received %v", ErrKeyNotFound, err)
+	}
+
+	if err = tdb.Rekey(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ring.Remove("2"); err != ErrKeyIsActive {
+		t.Fatalf("invalid error, expected %v and received %v", ErrKeyIsActive, err)
+	}
+
+	if err = ring.Remove("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = NewEncrypted("rekey", "./data", testMarshal, testUnmarshal, NewKeyRing("2", k2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		_, err = txn.Get("1")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Disk-resident values are re-sealed with the active key as well
+	ring = NewKeyRing("1", k1)
+	if tdb, err = Open[any]("bounded", "./data", WithCodec(testMarshal, testUnmarshal), WithEncryption(ring), WithBounded(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ring.Add("2", k2)
+	if err = ring.SetActive("2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Rekey(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The value file is re-used when re-opened, so it must be readable without the previous key
+	if tdb, err = Open[any]("bounded", "./data", WithCodec(testMarshal, testUnmarshal), WithEncryption(NewKeyRing("2", k2)), WithBounded(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values are not cached, so they are read from the value file
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		_, err = txn.Get("0")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var pdb *Turtle[any]
+	if pdb, err = New("plain", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = pdb.Rekey(); err != ErrNotEncrypted {
+		t.Fatalf("invalid error, expected %v and received %v", ErrNotEncrypted, err)
+	}
+
+	if err = pdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = NewCompressed("compressed", "./data", testMarshal, testUnmarshal, GzipCompressor{}); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	long := strings.Repeat("John Doe ", 100)
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("0", &testStruct{Name: long}); err != nil {
+			return
+		}
+
+		if err = txn.PutWithTTL("1", &testStruct{Name: "J"}, time.Hour); err != nil {
+			return
+		}
+
+		_, err = txn.CreateBucket("foo")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = NewCompressed("compressed", "./data", testMarshal, testUnmarshal, GzipCompressor{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		if name := val.(*testStruct).Name; name != long {
+			return fmt.Errorf("invalid value, expected %s and received %s", long, name)
+		}
+
+		if _, err = txn.Get("1"); err != nil {
+			return
+		}
+
+		_, err = txn.Bucket("foo")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values are only compressed when compression reduces their size
+	z := &recordCompressor{c: GzipCompressor{}}
+	for value, flag := range map[string]byte{long: recordCompressed, "J": recordRaw} {
+		b, err := z.encode([]byte(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b[0] != flag {
+			t.Fatalf("invalid compression flag for %q, expected %d and received %d", value, flag, b[0])
+		}
+
+		if b, err = z.decode(b); err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != value {
+			t.Fatalf("invalid value, expected %s and received %s", value, b)
+		}
+	}
+
+	if _, err = z.decode([]byte{9}); err != ErrInvalidCompressionFlag {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidCompressionFlag, err)
+	}
+
+	// Write a legacy log, without a format header
+	m, err := mrT.New("./data", "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Txn(func(txn *mrT.Txn) (err error) {
+		var b []byte
+		if b, err = testMarshal(&testStruct{Name: "John Doe"}); err != nil {
+			return
+		}
+
+		return txn.Put([]byte("0"), b)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Compression can be enabled for a legacy database, uncompressed and compressed records coexist
+	opts := []Option{WithCodec[any](testMarshal, testUnmarshal), WithCompression(GzipCompressor{}), WithSnapshotOnClose(false)}
+	for i := 0; i < 2; i++ {
+		if tdb, err = Open[any]("legacy", "./data", opts...); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put(strconv.Itoa(i+1), &testStruct{Name: long})
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = tdb.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if tdb, err = Open[any]("legacy", "./data", opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Legacy record, format header, and a compressed record for each commit
+	if s := tdb.Stats(); s.LogRecords != 4 {
+		t.Fatalf("invalid number of log records, expected %d and received %d", 4, s.LogRecords)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		for key, name := range map[string]string{"0": "John Doe", "1": long, "2": long} {
+			var val any
+			if val, err = txn.Get(key); err != nil {
+				return
+			}
+
+			if val.(*testStruct).Name != name {
+				return fmt.Errorf("invalid value for %s, expected %s and received %s", key, name, val.(*testStruct).Name)
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Compressed records cannot be read without a compressor
+	if _, err = New("legacy", "./data", testMarshal, testUnmarshal); err != ErrFormatMismatch {
+		t.Fatalf("invalid error, expected %v and received %v", ErrFormatMismatch, err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	defer os.RemoveAll("./data")
+
+	key := StaticKey(bytes.Repeat([]byte{1}, 32))
+	checksums := []Option{WithCodec[any](testMarshal, testUnmarshal), WithChecksums()}
+	compressed := []Option{WithCodec[any](testMarshal, testUnmarshal), WithCompression(GzipCompressor{})}
+	encrypted := []Option{WithCodec[any](testMarshal, testUnmarshal), WithEncryption(key)}
+	plain := []Option{WithCodec[any](testMarshal, testUnmarshal)}
+	for name, opts := range map[string][]Option{"checksums": checksums, "compressed": compressed, "encrypted": encrypted, "plain": plain} {
+		if tdb, err = Open[any](name, "./data", opts...); err != nil {
+			t.Fatal(err)
+		}
+
+		// Format header is written with the first commit
+		if err = tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put("0", &testStruct{Name: "John Doe"})
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = tdb.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mismatches := map[string][][]Option{
+		"checksums":  {plain, compressed, append(checksums, WithEncryption(key))},
+		"compressed": {plain, checksums},
+		"encrypted":  {plain, checksums},
+		"plain":      {checksums, encrypted},
+	}
+
+	for name, optss := range mismatches {
+		for i, opts := range optss {
+			if _, err = Open[any](name, "./data", opts...); err != ErrFormatMismatch {
+				t.Fatalf("invalid error for %s #%d, expected %v and received %v", name, i, ErrFormatMismatch, err)
+			}
+
+			// Read-only databases are checked as well
+			if _, err = Open[any](name, "./data", append(opts, WithReadOnly())...); err != ErrFormatMismatch {
+				t.Fatalf("invalid error for read-only %s #%d, expected %v and received %v", name, i, ErrFormatMismatch, err)
+			}
+		}
+	}
+
+	// Format header is the first record of the log
+	m, err := mrT.New("./data", "checksums")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first []byte
+	if err = m.ForEach(func(lineType byte, key, value []byte) (end bool) {
+		if isFormatKey(key) {
+			first = value
+		}
+
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if f, err := decodeFormat(first); err != nil || f != formatChecksums {
+		t.Fatalf("invalid format header, expected %d and received %d (%v)", formatChecksums, f, err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = NewChecksummed("checksum", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		if err = txn.Put("0", &testStruct{Name: "John Doe"}); err != nil {
+			return
+		}
+
+		return txn.Put("1", &testStruct{Name: "Jane Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Delete("1")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = NewChecksummed("checksum", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("1"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		_, err = txn.Get("0")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Append a damaged record to the log
+	m, err := mrT.New("./data", "checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := &recordChecksum{}
+	if err = m.Txn(func(txn *mrT.Txn) (err error) {
+		if err = txn.Put(sum.append([]byte("2"), []byte(`{"name":"Foo"}`)), []byte(`{"name":"Bar"}`)); err != nil {
+			return
+		}
+
+		return txn.Put(sum.append([]byte("3"), []byte(`{"name":"Baz"}`)), []byte(`{"name":"Baz"}`))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewChecksummed("checksum", "./data", testMarshal, testUnmarshal)
+	cerr, ok := err.(*CorruptionError)
+	if !ok {
+		t.Fatalf("invalid error, expected *CorruptionError and received %v", err)
+	}
+
+	// Records are positioned after the format header
+	if cerr.Record != 2 || cerr.Key != "2" || cerr.Err != ErrChecksumMismatch {
+		t.Fatalf("invalid corruption error: %+v", cerr)
+	}
+
+	if cerr.File != filepath.Join("data", "checksum.tdb") {
+		t.Fatalf("invalid corruption error file, expected %s and received %s", filepath.Join("data", "checksum.tdb"), cerr.File)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		r   *RepairReport
+		err error
+	)
+
+	if tdb, err = NewChecksummed("repair", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, err = Repair("repair", "./data", RepairOptions{Checksums: true, Quarantine: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Format header and a single record
+	if r.Repaired() || r.Records != 2 {
+		t.Fatalf("invalid report, expected no repair of 2 records and received %+v", r)
+	}
+
+	// Append a damaged record followed by a healthy record
+	m, err := mrT.New("./data", "repair")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := &recordChecksum{}
+	if err = m.Txn(func(txn *mrT.Txn) (err error) {
+		if err = txn.Put(sum.append([]byte("1"), []byte(`{"name":"Foo"}`)), []byte(`{"name":"Bar"}`)); err != nil {
+			return
+		}
+
+		return txn.Put(sum.append([]byte("2"), []byte(`{"name":"Baz"}`)), []byte(`{"name":"Baz"}`))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewChecksummed("repair", "./data", testMarshal, testUnmarshal); err == nil {
+		t.Fatal("expected error loading damaged database")
+	}
+
+	if r, err = Repair("repair", "./data", RepairOptions{Checksums: true, Quarantine: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.Repaired() || r.Records != 4 || len(r.Discarded) != 1 || r.Truncated != nil {
+		t.Fatalf("invalid report: %+v", r)
+	}
+
+	if cerr := r.Discarded[0]; cerr.Record != 2 || cerr.Key != "1" || cerr.Err != ErrChecksumMismatch {
+		t.Fatalf("invalid corruption error: %+v", cerr)
+	}
+
+	// Discarded record is kept as-is within the quarantine log
+	if m, err = mrT.New("./data", r.Quarantine); err != nil {
+		t.Fatal(err)
+	}
+
+	var quarantined int
+	if err = m.ForEach(func(lineType byte, key, value []byte) (end bool) {
+		if string(value) != `{"name":"Bar"}` {
+			t.Fatalf("invalid quarantined value: %s", value)
+		}
+
+		quarantined++
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if quarantined != 1 {
+		t.Fatalf("invalid number of quarantined records, expected %d and received %d", 1, quarantined)
+	}
+
+	if tdb, err = NewChecksummed("repair", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		if _, err = txn.Get("1"); err != ErrKeyDoesNotExist {
+			return fmt.Errorf("invalid error, expected %v and received %v", ErrKeyDoesNotExist, err)
+		}
+
+		if _, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		_, err = txn.Get("2")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records cannot be read with the wrong options, the log must be left as-is
+	if _, err = Repair("repair", "./data", RepairOptions{Key: StaticKey(make([]byte, 32))}); err != ErrFormatMismatch {
+		t.Fatalf("invalid error, expected %v and received %v", ErrFormatMismatch, err)
+	}
+
+	key := StaticKey(bytes.Repeat([]byte{1}, 32))
+	if tdb, err = NewEncrypted("repair-key", "./data", testMarshal, testUnmarshal, key); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records cannot be read with the wrong key, the log must be left as-is
+	if _, err = Repair("repair-key", "./data", RepairOptions{Key: StaticKey(make([]byte, 32))}); err != ErrNothingRecovered {
+		t.Fatalf("invalid error, expected %v and received %v", ErrNothingRecovered, err)
+	}
+
+	if tdb, err = NewEncrypted("repair-key", "./data", testMarshal, testUnmarshal, key); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tdb, err = NewChecksummed("repair", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDurability(t *testing.T) {
+	if os.Getenv("TURTLE_CRASH_CHILD") == "1" {
+		// Child process, write until killed
+		crashChild()
+		return
+	}
+
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	if tdb, err = New("durability", "./data", testMarshal, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./data")
+
+	if err = tdb.SetDurability(SyncInterval(0)); err != ErrInvalidDurability {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidDurability, err)
+	}
+
+	if err = tdb.SetDurability(SyncInterval(time.Millisecond * 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("0", &testStruct{Name: "John Doe"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the syncer to sync the commit
+	for i := 0; ; i++ {
+		tdb.mux.Lock()
+		unsynced := tdb.unsynced
+		tdb.mux.Unlock()
+		if !unsynced {
+			break
+		}
+
+		if i == 100 {
+			t.Fatal("commit was not synced within the sync interval")
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if err = tdb.SetDurability(NoSync); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The log is synced by name, so committed records must already be within the file of that name
+	// This fails if the back-end names the log differently, or buffers records after a commit returns
+	var dir string
+	if dir, err = copyLog("durability", "./data"); err != nil {
+		t.Fatalf("log file was not found where it is synced: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var rdb *Turtle[any]
+	if rdb, err = OpenReadOnly("durability", dir, testUnmarshal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = rdb.Read(func(txn Txn[any]) (err error) {
+		_, err = txn.Get("0")
+		return
+	}); err != nil {
+		t.Fatalf("committed record was not found within the log file: %v", err)
+	}
+
+	if err = rdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.SetDurability(SyncEveryCommit); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fail syncs, the transaction is committed but not durable
+	var synced []string
+	errSync := errors.Error("sync failed")
+	tdb.syncFn = func(filename string) error {
+		synced = append(synced, filename)
+		return errSync
+	}
+
+	err = tdb.Update(func(txn Txn[any]) (err error) {
+		return txn.Put("1", &testStruct{Name: "Jane Doe"})
+	})
+
+	if nerr, ok := err.(*NotDurableError); !ok || nerr.Err != errSync {
+		t.Fatalf("invalid error, expected *NotDurableError and received %v", err)
+	}
+
+	if len(synced) != 1 || synced[0] != logFile("durability", "./data") {
+		t.Fatalf("invalid synced files, expected %v and received %v", []string{logFile("durability", "./data")}, synced)
+	}
+
+	tdb.syncFn = syncFile
+	// Transaction was committed, and is left to be synced
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		_, err = txn.Get("1")
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Sync(); err != errors.ErrIsClosed {
+		t.Fatalf("invalid error, expected %v and received %v", errors.ErrIsClosed, err)
+	}
+
+	// Kill a child process mid-write, every acknowledged commit must survive
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDurability$")
+	cmd.Env = append(os.Environ(), "TURTLE_CRASH_CHILD=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var acked int
+	sc := bufio.NewScanner(stdout)
+	for acked < 100 && sc.Scan() {
+		if strings.HasPrefix(sc.Text(), "ack ") {
+			acked++
+		}
+	}
+
+	if err = cmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd.Wait()
+	if acked < 100 {
+		t.Fatalf("invalid number of acknowledged commits, expected %d and received %d", 100, acked)
+	}
+
+	if tdb, err = New("crash", "./data", testMarshal, testUnmarshal); err != nil {
+		// The child may have been killed while writing a transaction
+		if _, err = Repair("crash", "./data", RepairOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if tdb, err = New("crash", "./data", testMarshal, testUnmarshal); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		for i := 0; i < acked; i++ {
+			if _, err = txn.Get(strconv.Itoa(i)); err != nil {
+				return fmt.Errorf("acknowledged commit %d was lost: %v", i, err)
+			}
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// crashChild will commit keys in order, acknowledging each commit on stdout until the process is killed
+// Note: Killing a process does not lose writes held by the operating system, so this confirms
+// acknowledged commits are within the log rather than confirming they reached stable storage.
+func crashChild() {
+	tdb, err := New("crash", "./data", testMarshal, testUnmarshal)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if err = tdb.SetDurability(SyncEveryCommit); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for i := 0; ; i++ {
+		if err = tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put(strconv.Itoa(i), &testStruct{Name: "John Doe"})
+		}); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("ack %d\n", i)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	var (
+		tdb *Turtle[any]
+		err error
+	)
+
+	defer os.RemoveAll("./data")
+	if _, err = Open[any]("options", "./data"); err != ErrInvalidCodec {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidCodec, err)
+	}
+
+	if _, err = Open[string]("options", "./data", WithCodec(testMarshal, testUnmarshal)); err != ErrInvalidCodec {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidCodec, err)
+	}
+
+	if _, err = New("options", "./data", testMarshal, testUnmarshal, WithBounded(-1)); err != ErrInvalidCacheSize {
+		t.Fatalf("invalid error, expected %v and received %v", ErrInvalidCacheSize, err)
+	}
+
+	var logs bytes.Buffer
+	opts := []Option{
+		WithCodec(testMarshal, testUnmarshal),
+		WithLogger(log.New(&logs, "", 0)),
+		WithBounded(0),
+		WithEncryption(StaticKey(bytes.Repeat([]byte{1}, 32))),
+		WithCompression(GzipCompressor{}),
+		WithChecksums(),
+		WithDurability(SyncEveryCommit),
+		WithSnapshotOnClose(false),
+	}
+
+	if tdb, err = Open[any]("options", "./data", opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = tdb.Update(func(txn Txn[any]) (err error) {
+			return txn.Put("0", &testStruct{Name: "John Doe " + strconv.Itoa(i)})
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Value file is encrypted along with the log
+	var matches []string
+	if matches, err = filepath.Glob("./data/options.values*"); err != nil || len(matches) != 1 {
+		t.Fatalf("invalid value files, expected %d and received %v (%v)", 1, matches, err)
+	}
+
+	var b []byte
+	if b, err = os.ReadFile(matches[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b) == 0 || bytes.Contains(b, []byte("John Doe")) {
+		t.Fatalf("invalid value file, expected encrypted values and received %q", b)
+	}
+
+	tdb.logf("turtle: %s", "hello")
+	if logs.String() != "turtle: hello\n" {
+		t.Fatalf("invalid log output, expected %q and received %q", "turtle: hello\n", logs.String())
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Open[any]("options", "./data", append(opts, WithReadOnly())...); err != ErrReadOnlyBounded {
+		t.Fatalf("invalid error, expected %v and received %v", ErrReadOnlyBounded, err)
+	}
+
+	// Read-only databases hold values in memory, drop the bounded option
+	readOnly := append([]Option{WithReadOnly()}, opts[:2]...)
+	if tdb, err = Open[any]("options", "./data", append(readOnly, opts[3:]...)...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Log was not replaced on close, the format header and every put remain within the log
+	if s := tdb.Stats(); s.LogRecords != 4 {
+		t.Fatalf("invalid number of log records, expected %d and received %d", 4, s.LogRecords)
+	}
+
+	if err = tdb.Read(func(txn Txn[any]) (err error) {
+		var val any
+		if val, err = txn.Get("0"); err != nil {
+			return
+		}
+
+		if name := val.(*testStruct).Name; name != "John Doe 2" {
+			return fmt.Errorf("invalid value, expected %s and received %s", "John Doe 2", name)
+		}
+
+		return
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tdb.Update(func(txn Txn[any]) (err error) {
+		return
+	}); err != ErrReadOnly {
+		t.Fatalf("invalid error, expected %v and received %v", ErrReadOnly, err)
+	}
+
+	if err = tdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,238 @@
+package turtle
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// newTx will return a new transaction handle
+// Note: The write-lock is expected to be held by the caller for writable transactions
+func newTx[V any](t *Turtle[V], writable bool) *Tx[V] {
+	var tx Tx[V]
+	tx.t = t
+	tx.writable = writable
+	if writable {
+		tx.w = t.newWTxn()
+		tx.txn = tx.w
+	} else {
+		tx.txn = t.newRTxn()
+	}
+
+	// Set leak guard, this will be called if the handle is garbage collected before being finished
+	runtime.SetFinalizer(&tx, (*Tx[V]).leaked)
+	return &tx
+}
+
+// Tx is a manually managed transaction handle
+// Every transaction must be finished by calling Commit or Rollback, writable
+// transactions block all other writers until they are finished
+type Tx[V any] struct {
+	// Underlying transaction
+	// Note: This is not embedded, every call must go through the finished state checks
+	txn Txn[V]
+
+	// Parent database
+	t *Turtle[V]
+	// Underlying write transaction, nil for read transactions
+	w *WTxn[V]
+	// Writable state
+	writable bool
+	// Finished state
+	done uint32
+}
+
+// finish will release the resources held by the transaction
+// A value of false is returned if the transaction was already finished
+func (tx *Tx[V]) finish() bool {
+	if !atomic.CompareAndSwapUint32(&tx.done, 0, 1) {
+		// Transaction has already been finished
+		return false
+	}
+
+	// Transaction is finished, the leak guard is no longer needed
+	runtime.SetFinalizer(tx, nil)
+	return true
+}
+
+// release will clear the transaction and release the write-lock for writable transactions
+func (tx *Tx[V]) release() {
+	tx.clear()
+	if tx.writable {
+		tx.t.mux.Unlock()
+	}
+}
+
+// clear will clear the underlying transaction
+func (tx *Tx[V]) clear() {
+	tx.txn.clear()
+}
+
+// leaked is called by the leak guard when an unfinished transaction is garbage collected
+func (tx *Tx[V]) leaked() {
+	if tx.isDone() {
+		return
+	}
+
+	tx.t.logf("turtle: transaction (writable: %v) was garbage collected without calling Commit or Rollback, rolling back", tx.writable)
+	tx.Rollback()
+}
+
+// isDone will return whether or not the transaction has been finished
+func (tx *Tx[V]) isDone() bool {
+	return atomic.LoadUint32(&tx.done) == 1
+}
+
+// Writable will return whether or not the transaction is writable
+func (tx *Tx[V]) Writable() bool {
+	return tx.writable
+}
+
+// Commit will commit the transaction
+// Committing a read transaction simply finishes it
+func (tx *Tx[V]) Commit() (err error) {
+	if !tx.finish() {
+		return ErrTxDone
+	}
+	// Defer release of transaction
+	defer tx.release()
+
+	if !tx.writable {
+		// Nothing to commit for read transactions
+		return
+	}
+
+	return tx.t.commit(tx.w)
+}
+
+// Rollback will discard the transaction
+func (tx *Tx[V]) Rollback() (err error) {
+	if !tx.finish() {
+		return ErrTxDone
+	}
+
+	tx.release()
+	return
+}
+
+// Get will get a value for a provided key
+func (tx *Tx[V]) Get(key string) (value V, err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return value, ErrTxDone
+	}
+
+	return tx.txn.Get(key)
+}
+
+// Put will put a value for a provided key
+func (tx *Tx[V]) Put(key string, value V) (err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return ErrTxDone
+	}
+
+	return tx.txn.Put(key, value)
+}
+
+// PutWithTTL will put a value for a provided key which expires after the provided duration
+func (tx *Tx[V]) PutWithTTL(key string, value V, ttl time.Duration) (err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return ErrTxDone
+	}
+
+	return tx.txn.PutWithTTL(key, value, ttl)
+}
+
+// Delete will delete a key
+func (tx *Tx[V]) Delete(key string) (err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return ErrTxDone
+	}
+
+	return tx.txn.Delete(key)
+}
+
+// ForEach will iterate through all current items
+func (tx *Tx[V]) ForEach(fn ForEachFn[V]) (err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return ErrTxDone
+	}
+
+	return tx.txn.ForEach(fn)
+}
+
+// ForEachPrefix will iterate in order through all current items with a matching key prefix
+func (tx *Tx[V]) ForEachPrefix(prefix string, fn ForEachFn[V]) (err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return ErrTxDone
+	}
+
+	return tx.txn.ForEachPrefix(prefix, fn)
+}
+
+// ForEachRange will iterate in order through all current items with a key between start (inclusive) and end (exclusive)
+func (tx *Tx[V]) ForEachRange(start, end string, fn ForEachFn[V]) (err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return ErrTxDone
+	}
+
+	return tx.txn.ForEachRange(start, end, fn)
+}
+
+// Index will return the secondary index matching the provided name
+// A nil index is returned once the transaction has been finished
+func (tx *Tx[V]) Index(name string) *Index[V] {
+	if tx.isDone() {
+		return nil
+	}
+
+	return tx.txn.Index(name)
+}
+
+// Cursor will return an ordered cursor
+// Once the transaction has been finished, the cursor is empty and Err returns ErrTxDone
+func (tx *Tx[V]) Cursor() *Cursor[V] {
+	if tx.isDone() {
+		c := newCursor[V](store[V]{}, nil)
+		c.err = ErrTxDone
+		return c
+	}
+
+	return tx.txn.Cursor()
+}
+
+// Bucket will return the bucket matching the provided name
+func (tx *Tx[V]) Bucket(name string) (bucket Bucket[V], err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return nil, ErrTxDone
+	}
+
+	return tx.txn.Bucket(name)
+}
+
+// CreateBucket will create a bucket for the provided name
+func (tx *Tx[V]) CreateBucket(name string) (bucket Bucket[V], err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return nil, ErrTxDone
+	}
+
+	return tx.txn.CreateBucket(name)
+}
+
+// DeleteBucket will delete the bucket matching the provided name
+func (tx *Tx[V]) DeleteBucket(name string) (err error) {
+	if tx.isDone() {
+		// Transaction has been finished, return with error
+		return ErrTxDone
+	}
+
+	return tx.txn.DeleteBucket(name)
+}
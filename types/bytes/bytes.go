@@ -1,9 +1,235 @@
 package bytes
 
-// New will return a new database
-func New(name, path string, mfn MarshalFn, ufn UnmarshalFn) (dbp *DB, err error) {
+import (
+	"io"
+	"time"
+
+	"github.com/itsmontoya/turtle"
+)
+
+const (
+	// ErrNotWriteTxn is returned when PUT or DELETE are called during a read txn
+	ErrNotWriteTxn = turtle.ErrNotWriteTxn
+	// ErrKeyDoesNotExist is returned when a key does not exist
+	ErrKeyDoesNotExist = turtle.ErrKeyDoesNotExist
+	// ErrInvalidKey is returned when a key begins with a null byte
+	ErrInvalidKey = turtle.ErrInvalidKey
+	// ErrBucketDoesNotExist is returned when a bucket does not exist
+	ErrBucketDoesNotExist = turtle.ErrBucketDoesNotExist
+	// ErrBucketExists is returned when creating a bucket which already exists
+	ErrBucketExists = turtle.ErrBucketExists
+	// ErrInvalidBucketName is returned when a bucket name is empty or contains a null byte
+	ErrInvalidBucketName = turtle.ErrInvalidBucketName
+	// ErrTxDone is returned when a transaction is used after Commit or Rollback have been called
+	ErrTxDone = turtle.ErrTxDone
+	// ErrInvalidSavepoint is returned when rolling back to a savepoint which is not valid for a transaction
+	ErrInvalidSavepoint = turtle.ErrInvalidSavepoint
+	// ErrInvalidTTL is returned when a TTL is not greater than zero
+	ErrInvalidTTL = turtle.ErrInvalidTTL
+	// ErrIndexDoesNotExist is returned when an index does not exist
+	ErrIndexDoesNotExist = turtle.ErrIndexDoesNotExist
+	// ErrIndexExists is returned when creating an index which already exists
+	ErrIndexExists = turtle.ErrIndexExists
+	// ErrInvalidIndexKey is returned when an index func returns an index key containing a null byte
+	ErrInvalidIndexKey = turtle.ErrInvalidIndexKey
+	// ErrInvalidCompactionPolicy is returned when a compaction policy has a negative size ratio
+	ErrInvalidCompactionPolicy = turtle.ErrInvalidCompactionPolicy
+	// ErrInvalidBackup is returned when restoring from a backup which is malformed or truncated
+	ErrInvalidBackup = turtle.ErrInvalidBackup
+	// ErrDatabaseExists is returned when restoring to a database which already contains data
+	ErrDatabaseExists = turtle.ErrDatabaseExists
+	// ErrInvalidFormat is returned when an export format is not supported or an import is malformed
+	ErrInvalidFormat = turtle.ErrInvalidFormat
+	// ErrReadOnly is returned when write actions are performed on a read-only database
+	ErrReadOnly = turtle.ErrReadOnly
+	// ErrNoMarshalFn is returned when values need to be marshaled by a database without a MarshalFn
+	ErrNoMarshalFn = turtle.ErrNoMarshalFn
+	// ErrInvalidCacheSize is returned when a cache size is negative
+	ErrInvalidCacheSize = turtle.ErrInvalidCacheSize
+	// ErrReadOnlyBounded is returned when a read-only database is opened with bounded values
+	ErrReadOnlyBounded = turtle.ErrReadOnlyBounded
+	// ErrInvalidEncryptionKey is returned when an encryption key is not 32 bytes
+	ErrInvalidEncryptionKey = turtle.ErrInvalidEncryptionKey
+	// ErrDecrypt is returned when a record cannot be decrypted, the key is incorrect or the record was modified
+	ErrDecrypt = turtle.ErrDecrypt
+	// ErrInvalidKeyID is returned when an encryption key ID is longer than 255 bytes
+	ErrInvalidKeyID = turtle.ErrInvalidKeyID
+	// ErrKeyNotFound is returned when a key provider does not have a key for a key ID
+	ErrKeyNotFound = turtle.ErrKeyNotFound
+	// ErrKeyIsActive is returned when removing the active key from a key ring
+	ErrKeyIsActive = turtle.ErrKeyIsActive
+	// ErrNotEncrypted is returned when re-keying a database which is not encrypted
+	ErrNotEncrypted = turtle.ErrNotEncrypted
+	// ErrInvalidCompressionFlag is returned when a record value has an unknown compression flag
+	ErrInvalidCompressionFlag = turtle.ErrInvalidCompressionFlag
+	// ErrChecksumMismatch is returned when a record does not match it's checksum
+	ErrChecksumMismatch = turtle.ErrChecksumMismatch
+	// ErrNothingRecovered is returned when a repair cannot read any of the records within a log
+	ErrNothingRecovered = turtle.ErrNothingRecovered
+	// ErrInvalidDurability is returned when a sync interval is not positive
+	ErrInvalidDurability = turtle.ErrInvalidDurability
+	// ErrInvalidCodec is returned when opening a database without a codec
+	ErrInvalidCodec = turtle.ErrInvalidCodec
+	// ErrInvalidMaxBatchSize is returned when a max batch size is not positive
+	ErrInvalidMaxBatchSize = turtle.ErrInvalidMaxBatchSize
+	// ErrInvalidMaxBatchDelay is returned when a max batch delay is negative
+	ErrInvalidMaxBatchDelay = turtle.ErrInvalidMaxBatchDelay
+	// ErrFormatMismatch is returned when a log holds records which cannot be read with the provided options
+	ErrFormatMismatch = turtle.ErrFormatMismatch
+
+	// FormatNDJSON is newline-delimited JSON, each line is a single key/value object
+	FormatNDJSON = turtle.FormatNDJSON
+)
+
+type (
+	// Txn is a basic transaction interface
+	Txn = turtle.Txn[[]byte]
+	// Bucket is a named key/value space within a transaction
+	Bucket = turtle.Bucket[[]byte]
+	// Cursor is an ordered iterator over keys
+	Cursor = turtle.Cursor[[]byte]
+	// Tx is a manually managed transaction handle
+	Tx = turtle.Tx[[]byte]
+	// Savepoint is a point within a write transaction which can be rolled back to
+	Savepoint = turtle.Savepoint[[]byte]
+	// CorruptionError is returned when a record within the log cannot be loaded
+	CorruptionError = turtle.CorruptionError
+	// RepairOptions are the options used to repair a database
+	RepairOptions = turtle.RepairOptions
+	// RepairReport describes the records discarded by a repair
+	RepairReport = turtle.RepairReport
+	// Durability determines when the log is synced to stable storage
+	Durability = turtle.Durability
+	// Option is used to configure a database, see Open
+	Option = turtle.Option
+	// Logger is used to report errors encountered by background goroutines
+	Logger = turtle.Logger
+	// NotDurableError is returned when a transaction was committed, but the log could not be synced
+	NotDurableError = turtle.NotDurableError
+	// PanicError is returned by Batch when the provided func panics
+	PanicError = turtle.PanicError
+	// Event is a committed change to a key
+	Event = turtle.Event[[]byte]
+	// Index is a secondary index within a transaction
+	Index = turtle.Index[[]byte]
+	// IndexFn is used to extract the index keys for a key/value pair
+	IndexFn = turtle.IndexFn[[]byte]
+	// UniqueError is returned when a transaction would violate a unique index
+	UniqueError = turtle.UniqueError
+	// CompactionPolicy determines when the log is automatically compacted
+	CompactionPolicy = turtle.CompactionPolicy
+	// Format is an export and import format
+	Format = turtle.Format
+	// ProgressFn is called after each import chunk is committed with the total number of imported keys
+	ProgressFn = turtle.ProgressFn
+	// Compressor compresses and decompresses record values
+	Compressor = turtle.Compressor
+	// GzipCompressor is a gzip Compressor
+	GzipCompressor = turtle.GzipCompressor
+	// KeyProvider provides the versioned keys used to encrypt records
+	KeyProvider = turtle.KeyProvider
+	// StaticKey is a KeyProvider for a single fixed key
+	StaticKey = turtle.StaticKey
+	// KeyRing is a KeyProvider for a set of versioned keys
+	KeyRing = turtle.KeyRing
+	// Stats are the statistics of a database
+	Stats = turtle.Stats
+	// RTxn is a read transaction
+	RTxn = turtle.RTxn[[]byte]
+	// WTxn is a write transaction
+	WTxn = turtle.WTxn[[]byte]
+	// ForEachFn is used for ForEach requests
+	ForEachFn = turtle.ForEachFn[[]byte]
+	// TxnFn is used for transactions
+	TxnFn = turtle.TxnFn[[]byte]
+	// MarshalFn is for marshaling
+	MarshalFn = turtle.MarshalFn[[]byte]
+	// UnmarshalFn is for unmarshaling
+	UnmarshalFn = turtle.UnmarshalFn[[]byte]
+)
+
+var (
+	// NoSync will never sync the log, see turtle.NoSync
+	NoSync = turtle.NoSync
+	// SyncEveryCommit will sync the log before each commit returns, see turtle.SyncEveryCommit
+	SyncEveryCommit = turtle.SyncEveryCommit
+)
+
+// New will return a new database, see turtle.New
+func New(name, path string, mfn MarshalFn, ufn UnmarshalFn, opts ...Option) (dbp *DB, err error) {
+	var db DB
+	if db.Turtle, err = turtle.New(name, path, mfn, ufn, opts...); err != nil {
+		return
+	}
+
+	dbp = &db
+	return
+}
+
+// Open will return a new database configured by the provided options, see turtle.Open
+func Open(name, path string, opts ...Option) (dbp *DB, err error) {
+	var db DB
+	if db.Turtle, err = turtle.Open[[]byte](name, path, opts...); err != nil {
+		return
+	}
+
+	dbp = &db
+	return
+}
+
+// NewBounded will return a new database which keeps values on disk, see turtle.NewBounded
+func NewBounded(name, path string, mfn MarshalFn, ufn UnmarshalFn, cacheSize int64) (dbp *DB, err error) {
+	var db DB
+	if db.Turtle, err = turtle.NewBounded(name, path, mfn, ufn, cacheSize); err != nil {
+		return
+	}
+
+	dbp = &db
+	return
+}
+
+// NewEncrypted will return a new database which encrypts records at rest, see turtle.NewEncrypted
+func NewEncrypted(name, path string, mfn MarshalFn, ufn UnmarshalFn, kp KeyProvider) (dbp *DB, err error) {
+	var db DB
+	if db.Turtle, err = turtle.NewEncrypted(name, path, mfn, ufn, kp); err != nil {
+		return
+	}
+
+	dbp = &db
+	return
+}
+
+// NewCompressed will return a new database which compresses values at rest, see turtle.NewCompressed
+func NewCompressed(name, path string, mfn MarshalFn, ufn UnmarshalFn, c Compressor) (dbp *DB, err error) {
+	var db DB
+	if db.Turtle, err = turtle.NewCompressed(name, path, mfn, ufn, c); err != nil {
+		return
+	}
+
+	dbp = &db
+	return
+}
+
+// NewChecksummed will return a new database which stores a checksum with each record, see turtle.NewChecksummed
+func NewChecksummed(name, path string, mfn MarshalFn, ufn UnmarshalFn) (dbp *DB, err error) {
+	var db DB
+	if db.Turtle, err = turtle.NewChecksummed(name, path, mfn, ufn); err != nil {
+		return
+	}
+
+	dbp = &db
+	return
+}
+
+// NewKeyRing will return a new key ring with the provided key set as the active key
+func NewKeyRing(id string, key []byte) *KeyRing {
+	return turtle.NewKeyRing(id, key)
+}
+
+// OpenReadOnly will return a new read-only database
+func OpenReadOnly(name, path string, ufn UnmarshalFn) (dbp *DB, err error) {
 	var db DB
-	if db.turtle, err = newTurtle(name, path, mfn, ufn); err != nil {
+	if db.Turtle, err = turtle.OpenReadOnly(name, path, ufn); err != nil {
 		return
 	}
 
@@ -11,7 +237,82 @@ func New(name, path string, mfn MarshalFn, ufn UnmarshalFn) (dbp *DB, err error)
 	return
 }
 
+// Restore will create a database at the provided path and name from a backup
+func Restore(r io.Reader, name, path string) error {
+	return turtle.Restore(r, name, path)
+}
+
+// Repair will salvage a database which cannot be loaded, see turtle.Repair
+func Repair(name, path string, opts RepairOptions) (*RepairReport, error) {
+	return turtle.Repair(name, path, opts)
+}
+
+// SyncInterval will sync the log periodically, see turtle.SyncInterval
+func SyncInterval(d time.Duration) Durability {
+	return turtle.SyncInterval(d)
+}
+
+// WithCodec will set the marshal and unmarshal funcs, see turtle.WithCodec
+func WithCodec(mfn MarshalFn, ufn UnmarshalFn) Option {
+	return turtle.WithCodec(mfn, ufn)
+}
+
+// WithLogger will set the logger used by background goroutines, see turtle.WithLogger
+func WithLogger(l Logger) Option {
+	return turtle.WithLogger(l)
+}
+
+// WithSnapshotOnClose will set whether or not the log is replaced on close, see turtle.WithSnapshotOnClose
+func WithSnapshotOnClose(snapshot bool) Option {
+	return turtle.WithSnapshotOnClose(snapshot)
+}
+
+// WithReadOnly will open the database read-only, see turtle.WithReadOnly
+func WithReadOnly() Option {
+	return turtle.WithReadOnly()
+}
+
+// WithBounded will keep values on disk, see turtle.WithBounded
+func WithBounded(cacheSize int64) Option {
+	return turtle.WithBounded(cacheSize)
+}
+
+// WithEncryption will encrypt records at rest, see turtle.WithEncryption
+func WithEncryption(kp KeyProvider) Option {
+	return turtle.WithEncryption(kp)
+}
+
+// WithCompression will compress values at rest, see turtle.WithCompression
+func WithCompression(c Compressor) Option {
+	return turtle.WithCompression(c)
+}
+
+// WithChecksums will store a checksum with each record, see turtle.WithChecksums
+func WithChecksums() Option {
+	return turtle.WithChecksums()
+}
+
+// WithDurability will set when the log is synced, see turtle.WithDurability
+func WithDurability(d Durability) Option {
+	return turtle.WithDurability(d)
+}
+
+// WithCompactionPolicy will set the automatic compaction policy, see turtle.WithCompactionPolicy
+func WithCompactionPolicy(p CompactionPolicy) Option {
+	return turtle.WithCompactionPolicy(p)
+}
+
+// WithMaxBatchSize will set the maximum number of calls within a batch, see turtle.WithMaxBatchSize
+func WithMaxBatchSize(n int) Option {
+	return turtle.WithMaxBatchSize(n)
+}
+
+// WithMaxBatchDelay will set the maximum delay before a batch is committed, see turtle.WithMaxBatchDelay
+func WithMaxBatchDelay(d time.Duration) Option {
+	return turtle.WithMaxBatchDelay(d)
+}
+
 // DB is a database
 type DB struct {
-	*turtle
+	*turtle.Turtle[[]byte]
 }
@@ -1,38 +1,249 @@
 package turtle
 
-// store is a basic data store
-type store map[string]Value
+import (
+	"sort"
+	"time"
+)
+
+// state is an immutable version of the database contents
+type state[V any] struct {
+	// Root store
+	s store[V]
+	// Buckets
+	b buckets[V]
+	// Expiry index, ordered by expiry (see getExpiryKey)
+	ttl store[struct{}]
+	// Secondary indexes for the root store
+	idx indexes[V]
+}
+
+// bucket will return the store for a provided bucket name, an empty name represents the root store
+func (st *state[V]) bucket(name string) store[V] {
+	if len(name) == 0 {
+		return st.s
+	}
+
+	return st.b[name]
+}
+
+// setBucket will set the store for a provided bucket name, an empty name represents the root store
+// Note: The buckets map is modified in place, callers must ensure it is not shared with readers
+func (st *state[V]) setBucket(name string, s store[V]) {
+	if len(name) == 0 {
+		st.s = s
+		return
+	}
+
+	st.b[name] = s
+}
+
+// unsetExpiry will remove the expiry index entry for a key, if it exists
+func (st *state[V]) unsetExpiry(name, key string, s store[V]) {
+	if n := s.root.get(key); n != nil && n.expires != 0 {
+		st.ttl = st.ttl.delete(getExpiryKey(n.expires, name, key))
+	}
+}
+
+// put will set the value, expiry and record size for a key within a bucket, maintaining the expiry index
+// When a value reference is provided the value is disk-resident, and is not held by the store
+func (st *state[V]) put(name, key string, value V, ref *valueRef[V], expires, size int64) {
+	s := st.bucket(name)
+	if len(name) == 0 && len(st.idx) > 0 {
+		// Root key, update secondary indexes
+		st.updateIndexes(key, s.root.get(key), &value)
+	}
+
+	st.unsetExpiry(name, key, s)
+	if expires != 0 {
+		st.ttl = st.ttl.put(getExpiryKey(expires, name, key), struct{}{}, 0, 0)
+	}
+
+	if ref != nil {
+		st.setBucket(name, s.putRef(key, ref, expires, size))
+		return
+	}
+
+	st.setBucket(name, s.put(key, value, expires, size))
+}
+
+// delete will remove a key within a bucket, maintaining the expiry index
+func (st *state[V]) delete(name, key string) {
+	s := st.bucket(name)
+	if n := s.root.get(key); n != nil && len(name) == 0 && len(st.idx) > 0 {
+		// Root key, update secondary indexes
+		st.updateIndexes(key, n, nil)
+	}
+
+	st.unsetExpiry(name, key, s)
+	st.setBucket(name, s.delete(key))
+}
+
+// deleteBucket will remove a bucket, maintaining the expiry index
+func (st *state[V]) deleteBucket(name string) {
+	st.b[name].root.walk(func(n *node[V]) (end bool) {
+		if n.expires != 0 {
+			st.ttl = st.ttl.delete(getExpiryKey(n.expires, name, n.key))
+		}
+
+		return
+	})
+
+	delete(st.b, name)
+}
+
+// apply will apply the actions of a transaction store to a bucket
+func (st *state[V]) apply(name string, ts txnStore[V]) {
+	for key, action := range ts {
+		if action.put {
+			if action.ref != nil {
+				// Value is disk-resident, cache the value as it was recently written
+				action.ref.vs.add(action.ref, action.value)
+			}
+
+			// Put action, update value for key
+			st.put(name, key, action.value, action.ref, action.expires, action.size)
+		} else {
+			// Delete action, remove key
+			st.delete(name, key)
+		}
+	}
+}
+
+// store is a basic, immutable data store
+// Updates return a new store which shares all untouched nodes with the original
+type store[V any] struct {
+	// Root node of the persistent tree
+	root *node[V]
+	// Number of keys
+	len int
+	// Total size of the records holding the values within the log
+	size int64
+}
 
 // get will retrieve a value for a provided key
-func (s store) get(key string) (value Value, err error) {
-	var ok bool
-	if value, ok = s[key]; !ok {
+func (s store[V]) get(key string) (value V, err error) {
+	var n *node[V]
+	if n = s.root.get(key); n == nil || n.isExpired(time.Now().UnixNano()) {
 		// Value does not exist for this key
 		err = ErrKeyDoesNotExist
+		return
 	}
 
-	return
+	return n.load()
 }
 
 // exists will return a boolean representing if a value exists for a provided key
-func (s store) exists(key string) (ok bool) {
-	_, ok = s[key]
+// Note: Expired keys exist until they have been removed
+func (s store[V]) exists(key string) (ok bool) {
+	return s.root.get(key) != nil
+}
+
+// put will return a copy of the store with the value, expiry and record size set for a provided key
+func (s store[V]) put(key string, value V, expires, size int64) store[V] {
+	return s.putNode(key, value, nil, expires, size)
+}
+
+// putRef will return a copy of the store with the disk-resident value, expiry and record size set for a provided key
+func (s store[V]) putRef(key string, ref *valueRef[V], expires, size int64) store[V] {
+	var value V
+	return s.putNode(key, value, ref, expires, size)
+}
+
+// putNode will return a copy of the store with the node for a provided key replaced
+func (s store[V]) putNode(key string, value V, ref *valueRef[V], expires, size int64) store[V] {
+	var added bool
+	if n := s.root.get(key); n != nil {
+		// Value is being replaced, it's record no longer counts towards the size
+		s.size -= n.size
+	}
+
+	if s.root, added = s.root.put(key, value, ref, expires, size); added {
+		s.len++
+	}
+
+	s.size += size
+	return s
+}
+
+// remap will return a copy of the store with the value reference of every disk-resident value replaced
+func (s store[V]) remap(fn func(*valueRef[V]) (*valueRef[V], error)) (out store[V], err error) {
+	out.len = s.len
+	out.size = s.size
+	out.root, err = s.root.remap(fn)
+	return
+}
+
+// delete will return a copy of the store with the provided key removed
+func (s store[V]) delete(key string) store[V] {
+	var removed bool
+	if n := s.root.get(key); n != nil {
+		s.size -= n.size
+	}
+
+	if s.root, removed = s.root.delete(key); removed {
+		s.len--
+	}
+
+	return s
+}
+
+// forEach will iterate through all unexpired items in key order
+func (s store[V]) forEach(fn ForEachFn[V]) (err error) {
+	now := time.Now().UnixNano()
+	s.root.walk(func(n *node[V]) (end bool) {
+		if n.isExpired(now) {
+			// Key has expired, skip
+			return
+		}
+
+		var value V
+		if value, err = n.load(); err != nil {
+			// Value could not be read, end early
+			return true
+		}
+
+		return fn(n.key, value)
+	})
+
 	return
 }
 
+// index is an ordered list of keys
+type index []string
+
+// ceil will return the position of the first key which is greater than or equal to the provided key
+func (i index) ceil(key string) int {
+	return sort.SearchStrings(i, key)
+}
+
+// after will return the position of the first key which is greater than the provided key
+func (i index) after(key string) (n int) {
+	if n = i.ceil(key); n < len(i) && i[n] == key {
+		n++
+	}
+
+	return
+}
+
+// before will return the position of the last key which is less than the provided key
+// A value of -1 is returned when no keys precede the provided key
+func (i index) before(key string) int {
+	return i.ceil(key) - 1
+}
+
 // txnStore is a specialized data store handling transaction actions
-type txnStore map[string]*action
+type txnStore[V any] map[string]*action[V]
 
 // get will retrieve a value for a provided key
-func (t txnStore) get(key string) (value Value, ok bool, err error) {
-	var a *action
+func (t txnStore[V]) get(key string) (value V, ok bool, err error) {
+	var a *action[V]
 	if a, ok = t[key]; !ok {
 		// No actions were taken for this key during the transaction
 		return
 	}
 
-	if !a.put {
-		// Key was deleted during this transaction, return early with error
+	if !a.put || a.isExpired(time.Now().UnixNano()) {
+		// Key was deleted (or has expired) during this transaction, return early with error
 		err = ErrKeyDoesNotExist
 		return
 	}
@@ -43,40 +254,92 @@ func (t txnStore) get(key string) (value Value, ok bool, err error) {
 }
 
 // exists will return a boolean representing if an action was taken for a provided key
-func (t txnStore) exists(key string) (ok bool) {
+func (t txnStore[V]) exists(key string) (ok bool) {
 	_, ok = t[key]
 	return
 }
 
-type action struct {
+// keys will return an ordered index of all the keys which had actions taken
+func (t txnStore[V]) keys() (idx index) {
+	idx = make(index, 0, len(t))
+	for key := range t {
+		idx = append(idx, key)
+	}
+
+	sort.Strings(idx)
+	return
+}
+
+type action[V any] struct {
 	// put state, false assumes a delete action
 	put bool
 	// value of action, only looked at during put state
-	value Value
+	value V
+	// expiry of action as unix nanoseconds, zero represents no expiry
+	expires int64
+	// disk-resident value reference, set when a put action is committed (see NewBounded)
+	ref *valueRef[V]
+	// size of the record holding the value within the log, set when a put action is committed
+	size int64
+}
+
+// isExpired will return whether or not the action has expired as of the provided time
+func (a *action[V]) isExpired(now int64) bool {
+	return a.put && a.expires != 0 && a.expires <= now
 }
 
 // Txn is a basic transaction interface
-type Txn interface {
+type Txn[V any] interface {
 	clear()
 
 	// Get value by key
-	Get(key string) (Value, error)
+	Get(key string) (V, error)
 	// Put value by key
-	Put(key string, value Value) error
+	Put(key string, value V) error
+	// PutWithTTL value by key, the key expires after the provided duration
+	PutWithTTL(key string, value V, ttl time.Duration) error
 	// Delete key
 	Delete(key string) error
 	// ForEach key/value pair
-	ForEach(fn ForEachFn) error
+	ForEach(fn ForEachFn[V]) error
+	// Index will return a secondary index by name
+	Index(name string) *Index[V]
+	// ForEachPrefix key/value pair with a matching key prefix, in order
+	ForEachPrefix(prefix string, fn ForEachFn[V]) error
+	// ForEachRange key/value pair with a key between start (inclusive) and end (exclusive), in order
+	ForEachRange(start, end string, fn ForEachFn[V]) error
+	// Cursor will return an ordered cursor
+	Cursor() *Cursor[V]
+
+	// Bucket will return a bucket by name
+	Bucket(name string) (Bucket[V], error)
+	// CreateBucket will create a bucket by name
+	CreateBucket(name string) (Bucket[V], error)
+	// DeleteBucket will delete a bucket by name
+	DeleteBucket(name string) error
 }
 
+// putFn is used to write back-end records
+type putFn func(key, value []byte) error
+
+// logTxn is used to write the lines of a back-end transaction
+type logTxn interface {
+	Put(key, value []byte) error
+	Delete(key []byte) error
+}
+
+// discardFn is called for records which cannot be loaded, returning nil will skip the record
+// The line type is NilLine (with a nil key and value) when the remainder of the log cannot be read
+type discardFn func(record int64, lineType byte, bkey, value []byte, err error) error
+
 // ForEachFn is used for ForEach requests
-type ForEachFn func(key string, value Value) (end bool)
+type ForEachFn[V any] func(key string, value V) (end bool)
 
 // TxnFn is used for transactions
-type TxnFn func(txn Txn) error
+type TxnFn[V any] func(txn Txn[V]) error
 
 // MarshalFn is for marshaling
-type MarshalFn func(Value) ([]byte, error)
+type MarshalFn[V any] func(V) ([]byte, error)
 
 // UnmarshalFn is for unmarshaling
-type UnmarshalFn func([]byte) (Value, error)
+type UnmarshalFn[V any] func([]byte) (V, error)
@@ -0,0 +1,524 @@
+package turtle
+
+import (
+	"container/list"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+const (
+	// indexMagic marks the end of a value file which was closed cleanly
+	indexMagic = "tvindex1"
+	// indexFooterSize is the size of the footer which follows a value file index
+	// The footer holds the index offset, log size and log modification time followed by the magic
+	indexFooterSize = 32
+)
+
+// valueRef is a reference to a disk-resident value
+type valueRef[V any] struct {
+	// Value file the value belongs to
+	vs *values[V]
+	// Offset of the marshaled value within the value file
+	off int64
+	// Length of the marshaled value
+	size int
+}
+
+// newValues will open the value file of a database within the provided directory
+// The value file is kept alongside the log and re-used across opens. When it was closed cleanly and the
+// log is unchanged since, the index written on close is loaded so that values are not written again while
+// the log is loaded (see lookup). Otherwise the value file is truncated and rebuilt from the log.
+// Values are encrypted within the value file when a record cipher is provided.
+func newValues[V any](path, name string, ufn UnmarshalFn[V], cacheSize int64, c *recordCipher) (vp *values[V], err error) {
+	// Remove any replacement value file left behind by a crash during compaction
+	if err = os.Remove(valuesFile(name, path) + ".tmp"); err != nil && !os.IsNotExist(err) {
+		return
+	}
+
+	var v values[V]
+	if v.f, err = os.OpenFile(valuesFile(name, path), os.O_CREATE|os.O_RDWR, 0644); err != nil {
+		return
+	}
+
+	v.path = path
+	v.name = name
+	v.ufn = ufn
+	v.rc = c
+	v.c = newLRU[V](cacheSize)
+	if v.idx, v.off = v.readIndex(); v.idx == nil {
+		// Value file is stale, it is rebuilt as the log is loaded
+		v.off = 0
+	}
+
+	// Remove the index (or any stale values), so the value file is rebuilt if we crash before closing
+	if err = v.f.Truncate(v.off); err != nil {
+		v.f.Close()
+		return
+	}
+
+	vp = &v
+	return
+}
+
+// values is an append-only file of marshaled values, read through an LRU cache
+type values[V any] struct {
+	// Directory and database name, used to create replacement value files
+	path string
+	name string
+
+	// Value file
+	f *os.File
+	// Append offset, only modified while loading or while the write-lock is held
+	off int64
+	// Index loaded from the value file, only set while the log is loaded (see lookup)
+	idx map[indexKey]*valueRef[V]
+
+	ufn UnmarshalFn[V]
+	// Record cipher, nil unless records are encrypted
+	rc *recordCipher
+
+	// Cache mutex, readers modify the cache order
+	mux sync.Mutex
+	// Cache of unmarshaled values
+	c *lru[V]
+}
+
+// indexKey is the bucket and key of a value within a value file index
+type indexKey struct {
+	bucket string
+	key    string
+}
+
+// append will write a marshaled value to the value file and return it's reference
+func (v *values[V]) append(b []byte) (ref *valueRef[V], err error) {
+	if b, err = v.rc.sealBytes(b); err != nil {
+		return
+	}
+
+	return v.write(b)
+}
+
+// write will write sealed bytes to the value file and return their reference
+func (v *values[V]) write(b []byte) (ref *valueRef[V], err error) {
+	if _, err = v.f.WriteAt(b, v.off); err != nil {
+		return
+	}
+
+	ref = &valueRef[V]{vs: v, off: v.off, size: len(b)}
+	v.off += int64(len(b))
+	return
+}
+
+// read will return the marshaled bytes for a value reference
+func (v *values[V]) read(ref *valueRef[V]) (b []byte, err error) {
+	if b, err = v.readSealed(ref); err != nil {
+		return
+	}
+
+	return v.rc.openBytes(b)
+}
+
+// readSealed will return the bytes for a value reference as they are held within the value file
+func (v *values[V]) readSealed(ref *valueRef[V]) (b []byte, err error) {
+	b = make([]byte, ref.size)
+	if _, err = v.f.ReadAt(b, ref.off); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// get will return the value for a value reference, values are unmarshaled when they are not cached
+func (v *values[V]) get(ref *valueRef[V]) (value V, err error) {
+	var ok bool
+	v.mux.Lock()
+	value, ok = v.c.get(ref.off)
+	v.mux.Unlock()
+	if ok {
+		return
+	}
+
+	var b []byte
+	if b, err = v.read(ref); err != nil {
+		return
+	}
+
+	if value, err = v.ufn(b); err != nil {
+		return
+	}
+
+	v.add(ref, value)
+	return
+}
+
+// add will add a value to the cache
+func (v *values[V]) add(ref *valueRef[V], value V) {
+	v.mux.Lock()
+	v.c.add(ref.off, value, int64(ref.size))
+	v.mux.Unlock()
+}
+
+// lookup will return the reference of a value held by the index loaded from the value file
+// The index describes the final state of the log, so it is only valid while the log is loaded.
+func (v *values[V]) lookup(bucket, key string) (ref *valueRef[V], ok bool) {
+	ref, ok = v.idx[indexKey{bucket: bucket, key: key}]
+	return
+}
+
+// close will close the value file
+// The value file is kept, though it is only re-used once an index has been written (see writeIndex).
+func (v *values[V]) close() error {
+	return v.f.Close()
+}
+
+// rewrite will copy the values referenced by a state into a replacement value file
+// Only live values are copied, so the replacement does not hold any values which have been replaced or
+// deleted. The references of the copied values are returned, keyed by their reference within this value file.
+// Note: The write-lock is not required, values committed after the state are copied by swapValues
+func (v *values[V]) rewrite(st *state[V]) (nv *values[V], moved map[*valueRef[V]]*valueRef[V], err error) {
+	var f *os.File
+	if f, err = os.Create(valuesFile(v.name, v.path) + ".tmp"); err != nil {
+		return
+	}
+
+	nv = &values[V]{path: v.path, name: v.name, f: f, ufn: v.ufn, rc: v.rc, c: newLRU[V](v.c.budget)}
+	moved = make(map[*valueRef[V]]*valueRef[V])
+	// copyStore will copy the values of a store, in key order
+	copyStore := func(s store[V]) {
+		s.root.walk(func(n *node[V]) (end bool) {
+			if n.ref == nil {
+				return
+			}
+
+			if moved[n.ref], err = nv.copy(n.ref); err != nil {
+				return true
+			}
+
+			return
+		})
+	}
+
+	if copyStore(st.s); err == nil {
+		for _, s := range st.b {
+			if copyStore(s); err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		nv.discard()
+		return nil, nil, err
+	}
+
+	return
+}
+
+// copy will copy a value from another value file and return it's new reference
+// Values are re-sealed with the active key, so the keys of replaced values are no longer needed once
+// the value file has been rewritten (see Turtle.Rekey). Cached values are carried over.
+func (v *values[V]) copy(ref *valueRef[V]) (out *valueRef[V], err error) {
+	var b []byte
+	if b, err = ref.vs.read(ref); err != nil {
+		return
+	}
+
+	if out, err = v.append(b); err != nil {
+		return
+	}
+
+	ref.vs.mux.Lock()
+	value, ok := ref.vs.c.get(ref.off)
+	ref.vs.mux.Unlock()
+	if ok {
+		v.add(out, value)
+	}
+
+	return
+}
+
+// discard will close and remove a replacement value file which is not being used
+func (v *values[V]) discard() {
+	v.f.Close()
+	os.Remove(v.f.Name())
+}
+
+// retire will release a value file which has been replaced
+// In-flight readers may still hold references to the value file, so it is closed once it is no longer referenced.
+func (v *values[V]) retire() {
+	f := v.f
+	runtime.SetFinalizer(v, func(*values[V]) {
+		f.Close()
+	})
+}
+
+// compactValues will replace the value file with a value file holding only the values of the current state
+// Note: The write-lock is expected to be held by the caller
+func (t *Turtle[V]) compactValues() (err error) {
+	var (
+		nv    *values[V]
+		moved map[*valueRef[V]]*valueRef[V]
+	)
+
+	if nv, moved, err = t.vs.rewrite(t.st.Load()); err != nil {
+		return
+	}
+
+	return t.swapValues(nv, moved)
+}
+
+// swapValues will replace the value file with a value file rewritten from a previous state (see rewrite)
+// Values which were committed after the state are copied, the current state is remapped to the new value file.
+// Note: The write-lock is expected to be held by the caller
+func (t *Turtle[V]) swapValues(nv *values[V], moved map[*valueRef[V]]*valueRef[V]) (err error) {
+	remap := func(ref *valueRef[V]) (*valueRef[V], error) {
+		if out, ok := moved[ref]; ok {
+			return out, nil
+		}
+
+		// Value was committed after the rewritten state
+		return nv.copy(ref)
+	}
+
+	st := t.st.Load()
+	cp := *st
+	if cp.s, err = cp.s.remap(remap); err != nil {
+		nv.discard()
+		return
+	}
+
+	cp.b = make(buckets[V], len(st.b))
+	for name, s := range st.b {
+		if cp.b[name], err = s.remap(remap); err != nil {
+			nv.discard()
+			return
+		}
+	}
+
+	// Replace the value file, in-flight readers keep reading from the open file
+	if err = os.Rename(nv.f.Name(), valuesFile(t.name, t.path)); err != nil {
+		nv.discard()
+		return
+	}
+
+	t.st.Store(&cp)
+	t.vs.retire()
+	t.vs = nv
+	return
+}
+
+// writeIndex will write the index of the values referenced by a state to the end of the value file
+// The index is written on close, once the log will no longer change. It is only loaded when the size
+// and modification time of the log still match, so a log which is replaced or appended to after closing
+// causes the value file to be rebuilt.
+// Keys are held within the index, so it is sealed along with the values.
+func (v *values[V]) writeIndex(st *state[V], log string) (err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(log); err != nil {
+		return
+	}
+
+	var b []byte
+	// appendStore will append an entry for each disk-resident value within a store
+	appendStore := func(bucket string, s store[V]) {
+		s.root.walk(func(n *node[V]) (end bool) {
+			if n.ref == nil {
+				return
+			}
+
+			b = binary.AppendUvarint(b, uint64(len(bucket)))
+			b = append(b, bucket...)
+			b = binary.AppendUvarint(b, uint64(len(n.key)))
+			b = append(b, n.key...)
+			b = binary.AppendUvarint(b, uint64(n.ref.off))
+			b = binary.AppendUvarint(b, uint64(n.ref.size))
+			return
+		})
+	}
+
+	appendStore("", st.s)
+	for name, s := range st.b {
+		appendStore(name, s)
+	}
+
+	if b, err = v.rc.sealBytes(b); err != nil {
+		return
+	}
+
+	// Footer, the offset of the index followed by the size and modification time of the log
+	var footer [indexFooterSize]byte
+	binary.LittleEndian.PutUint64(footer[0:], uint64(v.off))
+	binary.LittleEndian.PutUint64(footer[8:], uint64(info.Size()))
+	binary.LittleEndian.PutUint64(footer[16:], uint64(info.ModTime().UnixNano()))
+	copy(footer[24:], indexMagic)
+	if _, err = v.f.WriteAt(append(b, footer[:]...), v.off); err != nil {
+		return
+	}
+
+	return v.f.Sync()
+}
+
+// readIndex will read the index written on close, a nil index is returned when the value file cannot be re-used
+func (v *values[V]) readIndex() (idx map[indexKey]*valueRef[V], off int64) {
+	var (
+		info os.FileInfo
+		log  os.FileInfo
+		err  error
+	)
+
+	if info, err = v.f.Stat(); err != nil || info.Size() < indexFooterSize {
+		return
+	}
+
+	var footer [indexFooterSize]byte
+	if _, err = v.f.ReadAt(footer[:], info.Size()-indexFooterSize); err != nil || string(footer[24:]) != indexMagic {
+		// Value file was not closed cleanly
+		return
+	}
+
+	if log, err = os.Stat(logFile(v.name, v.path)); err != nil {
+		return
+	}
+
+	off = int64(binary.LittleEndian.Uint64(footer[0:]))
+	if uint64(log.Size()) != binary.LittleEndian.Uint64(footer[8:]) ||
+		uint64(log.ModTime().UnixNano()) != binary.LittleEndian.Uint64(footer[16:]) {
+		// Log has changed since the value file was closed
+		return
+	}
+
+	if off < 0 || off > info.Size()-indexFooterSize {
+		return
+	}
+
+	b := make([]byte, info.Size()-indexFooterSize-off)
+	if _, err = v.f.ReadAt(b, off); err != nil {
+		return
+	}
+
+	if b, err = v.rc.openBytes(b); err != nil {
+		// Index was sealed with a key which is no longer available
+		return
+	}
+
+	idx = make(map[indexKey]*valueRef[V])
+	// next will return the next uvarint within the index
+	next := func() (n uint64) {
+		var size int
+		if n, size = binary.Uvarint(b); size <= 0 {
+			err = ErrInvalidFormat
+			return
+		}
+
+		b = b[size:]
+		return
+	}
+
+	// str will return the next length prefixed string within the index
+	str := func() (s string) {
+		n := next()
+		if err != nil || n > uint64(len(b)) {
+			err = ErrInvalidFormat
+			return
+		}
+
+		s, b = string(b[:n]), b[n:]
+		return
+	}
+
+	for len(b) > 0 && err == nil {
+		var k indexKey
+		k.bucket = str()
+		k.key = str()
+		ref := &valueRef[V]{vs: v, off: int64(next()), size: int(next())}
+		if err == nil && ref.off+int64(ref.size) > off {
+			// Value does not precede the index
+			err = ErrInvalidFormat
+		}
+
+		idx[k] = ref
+	}
+
+	if err != nil {
+		return nil, 0
+	}
+
+	return
+}
+
+// valuesFile will return the value file of a database
+func valuesFile(name, path string) string {
+	return filepath.Join(path, name+".values")
+}
+
+// newLRU will return a new LRU cache with the provided byte budget
+func newLRU[V any](budget int64) *lru[V] {
+	var l lru[V]
+	l.budget = budget
+	l.l = list.New()
+	l.m = make(map[int64]*list.Element)
+	return &l
+}
+
+// lru is a least recently used cache of values, keyed by value file offset
+// The size of a value is the length of it's bytes within the value file
+type lru[V any] struct {
+	// Maximum total size of the cached values
+	budget int64
+	// Current total size of the cached values
+	size int64
+
+	// Entries, most recently used first
+	l *list.List
+	// Entries by offset
+	m map[int64]*list.Element
+}
+
+// lruEntry is an entry within an LRU cache
+type lruEntry[V any] struct {
+	off   int64
+	value V
+	size  int64
+}
+
+// get will return the value for an offset and mark it as recently used
+func (l *lru[V]) get(off int64) (value V, ok bool) {
+	var e *list.Element
+	if e, ok = l.m[off]; !ok {
+		return
+	}
+
+	l.l.MoveToFront(e)
+	return e.Value.(*lruEntry[V]).value, true
+}
+
+// add will add a value for an offset, evicting the least recently used values to stay within budget
+// Values larger than the budget are not cached, empty values have a size of one
+func (l *lru[V]) add(off int64, value V, size int64) {
+	if size == 0 {
+		size = 1
+	}
+
+	if size > l.budget {
+		return
+	}
+
+	if e, ok := l.m[off]; ok {
+		// Offsets are never re-used, the value is already cached
+		l.l.MoveToFront(e)
+		return
+	}
+
+	l.m[off] = l.l.PushFront(&lruEntry[V]{off: off, value: value, size: size})
+	for l.size += size; l.size > l.budget; {
+		// Over budget, evict the least recently used value
+		e := l.l.Back()
+		le := e.Value.(*lruEntry[V])
+		l.l.Remove(e)
+		delete(l.m, le.off)
+		l.size -= le.size
+	}
+}
@@ -0,0 +1,228 @@
+package turtle
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxWatchQueue is the maximum number of events queued for a watcher
+const MaxWatchQueue = 4096
+
+// Event is a committed change to a key
+type Event[V any] struct {
+	// Sequence number of the commit which produced the event
+	// Note: Sequence numbers start from one each time the database is opened
+	Seq uint64
+	// Bucket of the key, empty for the root bucket
+	Bucket string
+	// Key which changed
+	Key string
+	// Value of the key, only set when Deleted is false
+	Value V
+	// Deleted state, true when the key was removed
+	Deleted bool
+	// Overflow state, true when events were dropped because the consumer fell behind
+	// Overflow events do not have a bucket, key or value. Any state derived from
+	// previous events should be rebuilt by reading the database.
+	Overflow bool
+}
+
+// Watch will return a channel of events for all committed changes to root keys beginning with the provided prefix
+// Changes to keys within buckets are not included (see WatchBucket).
+// Events are buffered for each watcher, so a slow consumer will never block writers.
+// Up to MaxWatchQueue events are buffered, once a consumer falls further behind every
+// buffered event is dropped and replaced by a single overflow event (see Event.Overflow).
+// The returned cancel func must be called once the watcher is no longer needed, the channel is
+// closed once the watcher is cancelled or the database is closed.
+func (t *Turtle[V]) Watch(prefix string) (events <-chan Event[V], cancel func()) {
+	return t.watch("", prefix)
+}
+
+// WatchBucket will return a channel of events for all committed changes to keys of a bucket beginning with the provided prefix
+// Events are delivered the same way as Watch, deleting the bucket produces a delete event for each of it's keys.
+func (t *Turtle[V]) WatchBucket(bucket, prefix string) (events <-chan Event[V], cancel func()) {
+	return t.watch(bucket, prefix)
+}
+
+// watch will return a channel of events for a bucket and key prefix, the root bucket is empty
+func (t *Turtle[V]) watch(bucket, prefix string) (events <-chan Event[V], cancel func()) {
+	w := newWatcher[V](bucket, prefix)
+
+	t.watchMux.Lock()
+	if t.isClosed() {
+		// DB is closed, no events will ever be sent
+		t.watchMux.Unlock()
+		w.stop()
+		return w.ch, func() {}
+	}
+
+	t.watchers[w] = struct{}{}
+	t.watchMux.Unlock()
+
+	cancel = func() {
+		t.watchMux.Lock()
+		delete(t.watchers, w)
+		t.watchMux.Unlock()
+		w.stop()
+	}
+
+	return w.ch, cancel
+}
+
+// notify will send the events for a committed transaction to all matching watchers
+// Note: The write-lock is expected to be held by the caller, which keeps events in commit order
+func (t *Turtle[V]) notify(txn *WTxn[V]) {
+	t.watchMux.RLock()
+	defer t.watchMux.RUnlock()
+
+	if len(t.watchers) == 0 {
+		// No watchers, avoid building events
+		return
+	}
+
+	evs := txn.events(t.seq)
+	for w := range t.watchers {
+		w.push(evs)
+	}
+}
+
+// closeWatchers will stop all watchers, closing their channels
+func (t *Turtle[V]) closeWatchers() {
+	t.watchMux.Lock()
+	defer t.watchMux.Unlock()
+
+	for w := range t.watchers {
+		delete(t.watchers, w)
+		w.stop()
+	}
+}
+
+// events will return the events for all of the actions taken within the transaction
+func (w *WTxn[V]) events(seq uint64) (evs []Event[V]) {
+	evs = appendEvents(evs, seq, "", w.ts)
+	for _, name := range w.tb.names() {
+		ba := w.tb[name]
+		if ba.reset {
+			// Original bucket was dropped, every original key is deleted unless it was put again
+			now := time.Now().UnixNano()
+			w.b[name].root.walk(func(n *node[V]) (end bool) {
+				if !n.isExpired(now) && (!ba.put || !ba.ts.exists(n.key)) {
+					evs = append(evs, Event[V]{Seq: seq, Bucket: name, Key: n.key, Deleted: true})
+				}
+
+				return
+			})
+		}
+
+		if ba.put {
+			evs = appendEvents(evs, seq, name, ba.ts)
+		}
+	}
+
+	return
+}
+
+// appendEvents will append the events for the actions of a transaction store, in key order
+func appendEvents[V any](evs []Event[V], seq uint64, name string, ts txnStore[V]) []Event[V] {
+	for _, key := range ts.keys() {
+		action := ts[key]
+		evs = append(evs, Event[V]{
+			Seq:     seq,
+			Bucket:  name,
+			Key:     key,
+			Value:   action.value,
+			Deleted: !action.put,
+		})
+	}
+
+	return evs
+}
+
+// newWatcher will return a new watcher and start it's delivery goroutine
+func newWatcher[V any](bucket, prefix string) *watcher[V] {
+	var w watcher[V]
+	w.bucket = bucket
+	w.prefix = prefix
+	w.ch = make(chan Event[V])
+	w.signal = make(chan struct{}, 1)
+	w.done = make(chan struct{})
+	go w.run()
+	return &w
+}
+
+// watcher delivers events for a bucket and key prefix
+type watcher[V any] struct {
+	// Bucket to match, empty for the root bucket
+	bucket string
+	// Key prefix to match
+	prefix string
+	// Outbound channel
+	ch chan Event[V]
+
+	// Queue mutex
+	mux sync.Mutex
+	// Events waiting to be delivered
+	queue []Event[V]
+	// Signal channel, notified when events are queued
+	signal chan struct{}
+	// Done channel, closed when the watcher is stopped
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// push will queue all of the provided events which match the watcher bucket and prefix
+// This never blocks on the consumer, when the queue is full it's events are replaced by an overflow event
+func (w *watcher[V]) push(evs []Event[V]) {
+	w.mux.Lock()
+	for _, ev := range evs {
+		if ev.Bucket != w.bucket || !strings.HasPrefix(ev.Key, w.prefix) {
+			continue
+		}
+
+		if len(w.queue) >= MaxWatchQueue {
+			// Consumer has fallen behind, drop the queued events
+			w.queue = []Event[V]{{Seq: ev.Seq, Overflow: true}}
+			continue
+		}
+
+		w.queue = append(w.queue, ev)
+	}
+	w.mux.Unlock()
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+		// Watcher has already been signaled
+	}
+}
+
+// run will deliver queued events until the watcher is stopped
+func (w *watcher[V]) run() {
+	defer close(w.ch)
+	for {
+		w.mux.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mux.Unlock()
+
+		for _, ev := range queue {
+			select {
+			case w.ch <- ev:
+			case <-w.done:
+				return
+			}
+		}
+
+		select {
+		case <-w.signal:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// stop will stop the watcher
+func (w *watcher[V]) stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
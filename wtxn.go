@@ -1,54 +1,138 @@
 package turtle
 
-import "github.com/itsmontoya/mrT"
-
 // WTxn is a write transaction
-type WTxn struct {
-	// Original store
-	s store
-	// Transaction store
-	ts txnStore
+type WTxn[V any] struct {
+	// Root bucket
+	wbucket[V]
+	// Original buckets
+	b buckets[V]
+	// Transaction buckets
+	tb txnBuckets[V]
+	// Original expiry index
+	ttl store[struct{}]
+	// Original secondary indexes
+	idx indexes[V]
 	// Marshal func
-	mfn MarshalFn
+	mfn MarshalFn[V]
+	// Value file, nil unless values are disk-resident
+	vs *values[V]
+	// Record cipher, nil unless records are encrypted
+	c *recordCipher
+	// Record compressor, nil unless values are compressed
+	z *recordCompressor
+	// Record checksum, nil unless records are checksummed
+	sum *recordChecksum
+	// Savepoint sequence, used to give each savepoint a unique ID
+	spSeq uint64
+	// Live savepoints, in the order they were created
+	sps []*Savepoint[V]
+	// Undo journal, changes made while savepoints are live
+	undos []undo[V]
+	// Root keys changed since the indexes were last checked
+	dirty map[string]struct{}
+	// Claimed index keys for each unique index
+	unique map[string]*uniqueClaims
+	// Number of records logged during commit
+	records uint64
+	// Size of the records logged during commit
+	size uint64
 }
 
-func (w *WTxn) clear() {
-	// Set store reference to nil
-	w.s = nil
+func (w *WTxn[V]) clear() {
+	// Set store reference to empty
+	w.s = store[V]{}
 	// Set transaction store reference to nil
 	w.ts = nil
+	// Set buckets reference to nil
+	w.b = nil
+	// Set transaction buckets reference to nil
+	w.tb = nil
+	// Set expiry index reference to empty
+	w.ttl = store[struct{}]{}
+	// Set secondary indexes reference to nil
+	w.idx = nil
+	// Set index check state references to nil
+	w.dirty = nil
+	w.unique = nil
 }
 
 // put is a QoL func to log a put action
-func (w *WTxn) put(txn *mrT.Txn, key string, value Value) (err error) {
+func (w *WTxn[V]) put(txn logTxn, key string, a *action[V]) (err error) {
 	var b []byte
 	// Attempt to marshal value as bytes
-	if b, err = w.mfn(value); err != nil {
+	if b, err = w.mfn(a.value); err != nil {
 		// Marshal error encountered, return
 		return
 	}
 
+	if w.vs != nil {
+		// Values are disk-resident, write the value to the value file
+		if a.ref, err = w.vs.append(b); err != nil {
+			return
+		}
+	}
+
 	// Log action to disk
-	if err = txn.Put([]byte(key), b); err != nil {
+	if a.size, err = w.logPut(txn, []byte(key), b); err != nil {
 		return
 	}
 
+	if w.records++; a.expires == 0 {
+		// No expiry to log
+		return
+	}
+
+	// Log expiry to disk, this must directly follow the put
+	w.records++
+	_, err = w.logPut(txn, []byte(getTTLKey(key)), encodeExpiry(a.expires))
 	return
 }
 
 // delete is a QoL func to log a delete action
-func (w *WTxn) delete(txn *mrT.Txn, key string) error {
-	// Log action to disk
-	return txn.Delete([]byte(key))
+func (w *WTxn[V]) delete(txn logTxn, key string) (err error) {
+	w.records++
+	var bkey []byte
+	// Seal key when records are encrypted
+	if bkey, err = w.c.sealKey([]byte(key)); err != nil {
+		return
+	}
+
+	// Log action to disk, appending a checksum when records are checksummed
+	bkey = w.sum.append(bkey, nil)
+	w.size += uint64(recordSize(bkey, nil))
+	return txn.Delete(bkey)
 }
 
-// commit will log all actions to disk
-func (w *WTxn) commit(txn *mrT.Txn) (err error) {
-	for key, action := range w.ts {
+// logPut will log a put line to disk, compressing the value, sealing the line and appending a checksum when enabled
+// The size of the logged record is returned
+func (w *WTxn[V]) logPut(txn logTxn, key, value []byte) (size int64, err error) {
+	if value, err = w.z.encode(value); err != nil {
+		return
+	}
+
+	if key, value, err = w.c.seal(key, value); err != nil {
+		return
+	}
+
+	key = w.sum.append(key, value)
+	size = recordSize(key, value)
+	w.size += uint64(size)
+	return size, txn.Put(key, value)
+}
+
+// commitStore will log all actions for a transaction store to disk
+// Keys are logged as-is when no bucket name is provided
+func (w *WTxn[V]) commitStore(txn logTxn, name string, ts txnStore[V]) (err error) {
+	for key, action := range ts {
+		if len(name) > 0 {
+			// Transaction store belongs to a bucket, encode the key
+			key = getBucketEntryKey(name, key)
+		}
+
 		// If action.put is true, put action
 		// Else, delete action
 		if action.put {
-			if err = w.put(txn, key, action.value); err != nil {
+			if err = w.put(txn, key, action); err != nil {
 				// Error encountered while logging put, return
 				return
 			}
@@ -63,86 +147,187 @@ func (w *WTxn) commit(txn *mrT.Txn) (err error) {
 	return
 }
 
-// merge will merge the transaction store values with the store values
-func (w *WTxn) merge() {
-	// Iterate through all transaction store actions
-	for key, action := range w.ts {
-		if action.put {
-			// Put action, update value for key
-			w.s[key] = action.value
-		} else {
-			// Delete action, remove key
-			delete(w.s, key)
+// commit will log all actions to disk
+func (w *WTxn[V]) commit(txn logTxn) (err error) {
+	w.records = 0
+	w.size = 0
+	if err = w.commitStore(txn, "", w.ts); err != nil {
+		return
+	}
+
+	for name, ba := range w.tb {
+		exists := w.b.exists(name)
+		if ba.reset && exists {
+			// Original bucket was dropped, log bucket delete
+			if err = w.delete(txn, getBucketKey(name)); err != nil {
+				return
+			}
+		}
+
+		if !ba.put {
+			// Bucket was deleted, no further actions are needed
+			continue
+		}
+
+		if ba.reset || !exists {
+			// Bucket is new, log bucket creation
+			if _, err = w.logPut(txn, []byte(getBucketKey(name)), nil); err != nil {
+				return
+			}
+
+			w.records++
+		}
+
+		if err = w.commitStore(txn, name, ba.ts); err != nil {
+			return
 		}
 	}
+
+	return
 }
 
-// Get will get a value for a provided key
-func (w *WTxn) Get(key string) (value Value, err error) {
-	var ok bool
-	// Attempt to get from transaction store first
-	if value, ok, err = w.ts.get(key); ok || err != nil {
-		// We've encountered two situations:
-		//	1. We've found the value (ok is true)
-		//	2. The value has been deleted during this transaction (err == ErrKeyDoesNotExist)
+// merge will return a new state with the transaction store values merged into the store values
+// The original state is left untouched so that it remains valid for any in-flight readers
+func (w *WTxn[V]) merge() (st *state[V]) {
+	st = &state[V]{s: w.s, b: w.b, ttl: w.ttl, idx: w.idx}
+	if len(w.ts) > 0 && len(w.idx) > 0 {
+		// Root actions were taken, copy the indexes so the original state remains untouched
+		st.idx = w.idx.copy()
+	}
+
+	// Merge root bucket
+	st.apply("", w.ts)
+
+	if len(w.tb) == 0 {
+		// No bucket actions were taken, we can share the original buckets
 		return
 	}
 
-	// Return results from get called directly on store
-	return w.s.get(key)
-}
+	// Copy the original buckets
+	st.b = make(buckets[V], len(w.b))
+	for name, s := range w.b {
+		st.b[name] = s
+	}
+
+	// Iterate through all transaction bucket actions
+	for name, ba := range w.tb {
+		if ba.reset && st.b.exists(name) {
+			// Original bucket was dropped, remove it
+			st.deleteBucket(name)
+		}
 
-// Put will put a value for a provided key
-func (w *WTxn) Put(key string, value Value) (err error) {
-	w.ts[key] = &action{
-		put:   true,
-		value: value,
+		if !ba.put {
+			// Delete action, nothing left to merge
+			continue
+		}
+
+		if !st.b.exists(name) {
+			// Bucket does not exist yet, create it
+			st.b[name] = store[V]{}
+		}
+
+		st.apply(name, ba.ts)
 	}
 
 	return
 }
 
-// Delete will delete a key
-func (w *WTxn) Delete(key string) (err error) {
-	if !w.s.exists(key) && !w.ts.exists(key) {
-		// This key does not exist within the store nor the transaction
-		// TODO: Add a better deletion use-case for transaction-only finds
-		return
+// Index will return the secondary index matching the provided name
+// Pending actions are captured when the index is retrieved
+func (w *WTxn[V]) Index(name string) *Index[V] {
+	// Values are read from the captured actions as well, so rolling back does not affect the index
+	b := wbucket[V]{s: w.s, ts: w.ts.copy()}
+	return newIndex(w.idx, name, b.ts, b.Get)
+}
+
+// bucketExists will return whether or not a bucket exists within the context of this transaction
+func (w *WTxn[V]) bucketExists(name string) bool {
+	if ba, ok := w.tb[name]; ok {
+		// An action was taken for this bucket during the transaction
+		return ba.put
 	}
 
-	// No value is needed as this is a delete action
-	w.ts[key] = &action{
-		put: false,
+	return w.b.exists(name)
+}
+
+// newBucket will return a writable bucket for a bucket action
+func (w *WTxn[V]) newBucket(name string, ba *bucketAction[V]) *wbucket[V] {
+	var bkt wbucket[V]
+	if !ba.reset {
+		// Original bucket contents are still in play, set store
+		// Note: Buckets which do not exist yet will return an empty store
+		bkt.s = w.b[name]
 	}
-	return
+
+	bkt.ts = ba.ts
+	bkt.txn = w
+	return &bkt
 }
 
-// ForEach will iterate through all current items
-func (w *WTxn) ForEach(fn ForEachFn) (err error) {
-	var ok bool
-	for key, action := range w.ts {
-		if !action.put {
-			// Action was not a PUT action, which means it was a delete action
-			continue
-		}
+// Bucket will return the bucket matching the provided name
+func (w *WTxn[V]) Bucket(name string) (bucket Bucket[V], err error) {
+	if !w.bucketExists(name) {
+		// Bucket does not exist, return early with error
+		return nil, ErrBucketDoesNotExist
+	}
 
-		if fn(key, action.value) {
-			// End was called, return early
-			return
+	ba, ok := w.tb[name]
+	if !ok {
+		// First access of this bucket during the transaction, create action
+		w.recordBucket(name)
+		ba = &bucketAction[V]{
+			ts:  make(txnStore[V]),
+			put: true,
 		}
+
+		w.tb[name] = ba
 	}
 
-	for key, value := range w.s {
-		if _, ok = w.ts[key]; ok {
-			// This key already exists within our transaction map, we can continue on
-			continue
-		}
+	return w.newBucket(name, ba), nil
+}
 
-		if fn(key, value) {
-			// End was called, return early
-			return
-		}
+// CreateBucket will create a bucket for the provided name
+func (w *WTxn[V]) CreateBucket(name string) (bucket Bucket[V], err error) {
+	if !isValidBucketName(name) {
+		// Bucket name cannot be encoded, return early with error
+		return nil, ErrInvalidBucketName
+	}
+
+	if w.bucketExists(name) {
+		// Bucket already exists, return early with error
+		return nil, ErrBucketExists
+	}
+
+	w.recordBucket(name)
+	ba, ok := w.tb[name]
+	if !ok {
+		ba = &bucketAction[V]{}
+		w.tb[name] = ba
+	}
+
+	// Any reset state is retained so a bucket deleted earlier in this transaction stays dropped
+	ba.ts = make(txnStore[V])
+	ba.put = true
+	return w.newBucket(name, ba), nil
+}
+
+// DeleteBucket will delete the bucket matching the provided name
+func (w *WTxn[V]) DeleteBucket(name string) (err error) {
+	if !w.bucketExists(name) {
+		// Bucket does not exist, return early with error
+		return ErrBucketDoesNotExist
+	}
+
+	w.recordBucket(name)
+	ba, ok := w.tb[name]
+	if !ok {
+		ba = &bucketAction[V]{}
+		w.tb[name] = ba
 	}
 
+	// No transaction store is needed as this is a delete action
+	ba.ts = nil
+	ba.reset = true
+	ba.put = false
 	return
 }